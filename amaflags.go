@@ -0,0 +1,102 @@
+package cel
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// AMAFlags are the Automated Message Accounting flags of a channel, which
+// tell billing systems how to treat its calls.
+type AMAFlags int
+
+// The AMA flags that Asterisk uses, with their standard codes.
+const (
+	AMAFlagsNone          AMAFlags = 0
+	AMAFlagsOmit          AMAFlags = 1
+	AMAFlagsBilling       AMAFlags = 2
+	AMAFlagsDocumentation AMAFlags = 3
+)
+
+var amaFlagsNames = map[AMAFlags]string{
+	AMAFlagsNone:          "NONE",
+	AMAFlagsOmit:          "OMIT",
+	AMAFlagsBilling:       "BILLING",
+	AMAFlagsDocumentation: "DOCUMENTATION",
+}
+
+// String returns the name of f, as in "BILLING".
+func (f AMAFlags) String() string {
+	if name, ok := amaFlagsNames[f]; ok {
+		return name
+	}
+	return fmt.Sprintf("AMAFlags(%d)", int(f))
+}
+
+// DefaultAMAFlagsCodes maps the standard codes of the AMA flags to the flags.
+var DefaultAMAFlagsCodes = map[int]AMAFlags{
+	0: AMAFlagsNone,
+	1: AMAFlagsOmit,
+	2: AMAFlagsBilling,
+	3: AMAFlagsDocumentation,
+}
+
+// WithAMAFlagsCodes makes the Decoder convert numeric values of AMAFlags
+// fields using codes, instead of DefaultAMAFlagsCodes, for deployments that
+// log AMA flags with other codes. MarshalEvent uses the same table in
+// reverse, using the lowest code for flags that have several.
+func WithAMAFlagsCodes(codes map[int]AMAFlags) Option {
+	return func(d *Decoder) {
+		d.cfg.amaFlagsCodes = codes
+	}
+}
+
+var amaFlagsType = reflect.TypeOf(AMAFlags(0))
+
+// convertAMAFlags accepts the code of the flags, their name (as in
+// "BILLING", in any case), or an empty string for AMAFlagsNone.
+func convertAMAFlags(v reflect.Value, s string, tagParts []string, c *config) error {
+	codes := c.amaFlagsCodes
+	if codes == nil {
+		codes = DefaultAMAFlagsCodes
+	}
+	if s == "" {
+		v.SetInt(int64(AMAFlagsNone))
+		return nil
+	}
+	if code, err := strconv.Atoi(s); err == nil {
+		f, ok := codes[code]
+		if !ok {
+			return errors.Errorf("unknown AMA flags code %d", code)
+		}
+		v.SetInt(int64(f))
+		return nil
+	}
+	for f, name := range amaFlagsNames {
+		if strings.EqualFold(s, name) {
+			v.SetInt(int64(f))
+			return nil
+		}
+	}
+	return errors.Errorf("unable to convert field value %q to AMA flags", s)
+}
+
+// formatAMAFlags is the inverse of convertAMAFlags.
+func formatAMAFlags(f AMAFlags, c *config) string {
+	if c.amaFlagsCodes == nil {
+		return strconv.Itoa(int(f))
+	}
+	code, found := 0, false
+	for k, v := range c.amaFlagsCodes {
+		if v == f && (!found || k < code) {
+			code, found = k, true
+		}
+	}
+	if !found {
+		code = int(f)
+	}
+	return strconv.Itoa(code)
+}
@@ -0,0 +1,54 @@
+package cel_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/VoIPGRID/cel"
+	"github.com/matryer/is"
+)
+
+func TestAMAFlags(t *testing.T) {
+	is := is.NewRelaxed(t)
+	var v struct {
+		Flags cel.AMAFlags `cel:"0"`
+	}
+	cases := []struct {
+		in   string
+		opts []cel.Option
+		want cel.AMAFlags
+	}{
+		{"3", nil, cel.AMAFlagsDocumentation},
+		{"2", nil, cel.AMAFlagsBilling},
+		{"", nil, cel.AMAFlagsNone},
+		{"billing", nil, cel.AMAFlagsBilling},
+		{"3", []cel.Option{cel.WithAMAFlagsCodes(map[int]cel.AMAFlags{1: cel.AMAFlagsOmit, 2: cel.AMAFlagsDocumentation, 3: cel.AMAFlagsBilling})}, cel.AMAFlagsBilling},
+	}
+	for _, c := range cases {
+		is.NoErr(cel.UnmarshalLine(c.in+",", &v, c.opts...))
+		is.Equal(v.Flags, c.want)
+	}
+	is.Equal(v.Flags.String(), "BILLING") // as configured
+	is.Equal(cel.AMAFlagsOmit.String(), "OMIT")
+	is.Equal(cel.AMAFlags(7).String(), "AMAFlags(7)")
+
+	err := cel.UnmarshalLine("7", &v)
+	is.Equal(fmt.Sprint(err), "failed to map field Flags: unknown AMA flags code 7")
+	err = cel.UnmarshalLine("sometimes", &v)
+	is.Equal(fmt.Sprint(err), `failed to map field Flags: unable to convert field value "sometimes" to AMA flags`)
+
+	// MarshalEvent uses the codes in reverse.
+	codes := cel.WithAMAFlagsCodes(map[int]cel.AMAFlags{5: cel.AMAFlagsBilling, 4: cel.AMAFlagsBilling})
+	v.Flags = cel.AMAFlagsBilling
+	record, err := cel.MarshalEvent(v, codes)
+	is.NoErr(err)
+	is.Equal(record, []string{"4"})
+	record, err = cel.MarshalEvent(v)
+	is.NoErr(err)
+	is.Equal(record, []string{"2"})
+
+	var e cel.Event
+	is.NoErr(cel.NewDecoder(strings.NewReader(cel.NewEventBuilder().Line())).Decode(&e))
+	is.Equal(e.AMAFlags, cel.AMAFlagsDocumentation)
+}
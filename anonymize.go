@@ -0,0 +1,71 @@
+package cel
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// An Anonymizer removes personal data from events, for sharing them with third
+// parties, while keeping them correlated: identifiers are replaced by
+// pseudonyms that are derived from the identifier using a keyed HMAC, so the
+// same identifier gets the same pseudonym in every event that is anonymized
+// with the same key, and no one without the key can recover it.
+type Anonymizer struct {
+	key []byte
+}
+
+// NewAnonymizer returns an Anonymizer that derives pseudonyms using key. Use
+// the same key for all events that should stay correlated, and keep it
+// secret.
+func NewAnonymizer(key []byte) *Anonymizer {
+	return &Anonymizer{key: key}
+}
+
+// Pseudonym returns the pseudonym for s: the first 16 hexadecimal digits of
+// its HMAC-SHA256. The empty string is returned as is.
+func (a *Anonymizer) Pseudonym(s string) string {
+	if s == "" {
+		return ""
+	}
+	h := hmac.New(sha256.New, a.key)
+	h.Write([]byte(s))
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// Anonymize returns a copy of e without personal data. UniqueID and LinkedID
+// are replaced by their pseudonyms, so that events can still be grouped into
+// channels and calls (see GroupByLinkedID and ChannelLifecycles). ChanName
+// and Peer are replaced as well, except for their technology (as in "SIP/"),
+// so that Local channels can still be recognized. The caller ID fields, Exten,
+// AppData, UserField and Extra, which may contain numbers or names, are
+// cleared. All other fields are kept.
+//
+// To anonymize a file, decode its events, anonymize them, and write them
+// using MarshalEvent.
+func (a *Anonymizer) Anonymize(e Event) Event {
+	e.UniqueID = a.Pseudonym(e.UniqueID)
+	e.LinkedID = a.Pseudonym(e.LinkedID)
+	e.ChanName = a.channel(e.ChanName)
+	e.Peer = a.channel(e.Peer)
+	e.CIDName = ""
+	e.CIDNum = ""
+	e.CIDANI = ""
+	e.CIDRDNIS = ""
+	e.CIDDNID = ""
+	e.Exten = ""
+	e.AppData = ""
+	e.UserField = ""
+	e.Extra = ""
+	return e
+}
+
+// channel returns the pseudonym for channel name s, keeping its technology.
+func (a *Anonymizer) channel(s string) string {
+	i := strings.Index(s, "/")
+	if i < 0 {
+		return a.Pseudonym(s)
+	}
+	return s[:i+1] + a.Pseudonym(s[i+1:])
+}
@@ -0,0 +1,64 @@
+package cel_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/VoIPGRID/cel"
+	"github.com/matryer/is"
+)
+
+func TestAnonymizer(t *testing.T) {
+	is := is.NewRelaxed(t)
+	in := `"CHAN_START","1530794700.000000","Alice","1001","1001","","2001","2001","from-internal","SIP/1001-01","","","3","acc1","1.1","1.1","","","",""
+"CHAN_START","1530794701.000000","","2001","","","","s","from-internal","SIP/2001-02","","","3","acc1","1.2","1.1","","","",""
+"ANSWER","1530794705.000000","","2001","","","","s","from-internal","SIP/2001-02","AppDial","(Outgoing Line)","3","acc1","1.2","1.1","","","",""
+"HANGUP","1530794730.000000","Alice","1001","1001","","2001","2001","from-internal","SIP/1001-01","Dial","SIP/2001","3","acc1","1.1","1.1","SIP/2001-02","","","{""dialstatus"":""ANSWER""}"
+`
+	a := cel.NewAnonymizer([]byte("secret"))
+	dec := cel.NewDecoder(strings.NewReader(in))
+	var out strings.Builder
+	var events []cel.Event
+	for {
+		var e cel.Event
+		err := dec.Decode(&e)
+		if err == io.EOF {
+			break
+		}
+		is.NoErr(err)
+		events = append(events, e)
+		record, err := cel.MarshalEvent(a.Anonymize(e))
+		is.NoErr(err)
+		out.WriteString(strings.Join(record, ",") + "\n")
+	}
+
+	for _, pii := range []string{"Alice", "1001", "2001", "1.1", "1.2", "dialstatus"} {
+		is.True(!strings.Contains(out.String(), pii)) // personal data removed
+	}
+
+	var anon []cel.Event
+	is.NoErr(cel.NewDecoder(strings.NewReader(out.String())).DecodeAll(&anon))
+	is.Equal(len(anon), 4)
+	is.Equal(anon[0].UniqueID, anon[0].LinkedID)
+	is.Equal(anon[0].UniqueID, anon[3].UniqueID)
+	is.Equal(anon[1].UniqueID, anon[2].UniqueID)
+	is.True(anon[0].UniqueID != anon[1].UniqueID)
+	is.Equal(anon[3].Peer, anon[1].ChanName)
+	is.True(strings.HasPrefix(anon[0].ChanName, "SIP/"))
+	is.Equal(anon[0].AccountCode, "acc1")
+	is.Equal(anon[0].Time, events[0].Time)
+
+	// Correlation is preserved: the anonymized call has the same shape.
+	calls := cel.GroupByLinkedID(anon)
+	is.Equal(len(calls), 1)
+	orig := cel.GroupByLinkedID(events)[0]
+	is.Equal(calls[0].OriginatingChannel(), a.Anonymize(events[0]).ChanName)
+	is.Equal(calls[0].AnsweringChannel(), a.Anonymize(events[2]).ChanName)
+	is.Equal(calls[0].EventCounts(), orig.EventCounts())
+
+	// Pseudonyms depend on the key.
+	is.True(cel.NewAnonymizer([]byte("other")).Pseudonym("1.1") != a.Pseudonym("1.1"))
+	is.Equal(a.Pseudonym("1.1"), a.Pseudonym("1.1"))
+	is.Equal(a.Pseudonym(""), "")
+}
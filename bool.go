@@ -0,0 +1,55 @@
+package cel
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// A BoolFormat is a representation of booleans in records.
+type BoolFormat struct {
+	True, False string
+}
+
+// Common representations of booleans.
+var (
+	BoolNumeric = BoolFormat{"1", "0"} // The default for marshaling.
+	BoolWords   = BoolFormat{"true", "false"}
+	BoolYesNo   = BoolFormat{"Y", "N"}
+)
+
+// WithBoolFormat makes bool fields use f. When decoding, values must match
+// f.True or f.False, ignoring case. When marshaling (see MarshalEvent), they
+// are written as f.True or f.False. Without this option, decoding accepts
+// anything strconv.ParseBool accepts, and marshaling uses BoolNumeric.
+func WithBoolFormat(f BoolFormat) Option {
+	return func(d *Decoder) {
+		d.cfg.boolFormat = &f
+	}
+}
+
+// parse parses s according to f, or using strconv.ParseBool if f is nil.
+func (f *BoolFormat) parse(s string) (bool, error) {
+	if f == nil {
+		return strconv.ParseBool(s)
+	}
+	switch {
+	case strings.EqualFold(s, f.True):
+		return true, nil
+	case strings.EqualFold(s, f.False):
+		return false, nil
+	}
+	return false, errors.Errorf("expected %q or %q", f.True, f.False)
+}
+
+// format formats b according to f, or BoolNumeric if f is nil.
+func (f *BoolFormat) format(b bool) string {
+	if f == nil {
+		f = &BoolNumeric
+	}
+	if b {
+		return f.True
+	}
+	return f.False
+}
@@ -0,0 +1,103 @@
+package cel
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// BridgeExtra holds the extra field of a BRIDGE_ENTER or BRIDGE_EXIT event.
+type BridgeExtra struct {
+	BridgeID         string `json:"bridge_id"`
+	BridgeTechnology string `json:"bridge_technology"` // As in "simple_bridge".
+}
+
+// ParseBridgeExtra parses the extra field of a BRIDGE_ENTER or BRIDGE_EXIT
+// event, like ParseBlindTransferExtra.
+func ParseBridgeExtra(extra string) (BridgeExtra, error) {
+	var x BridgeExtra
+	if err := json.Unmarshal([]byte(extra), &x); err != nil {
+		return BridgeExtra{}, errors.Wrap(err, "bad bridge extra")
+	}
+	return x, nil
+}
+
+// A BridgeSnapshot holds the channels that were in each bridge at a time.
+type BridgeSnapshot struct {
+	Time time.Time
+
+	// Bridges maps the ids of the bridges that have channels to the names of
+	// those channels, in the order in which they entered.
+	Bridges map[string][]string
+}
+
+// BridgeMembership reconstructs the members of the bridges that events refer
+// to, by applying their BRIDGE_ENTER and BRIDGE_EXIT events in order. It
+// returns a snapshot for every event time at which the membership changed,
+// taken after applying all events of that time. Events are expected in the
+// order they were logged, and may belong to several calls.
+//
+// A channel is in one bridge at a time: entering a bridge removes it from the
+// bridge it was in, in case its exit was not logged. Likewise, a CHAN_END
+// event removes its channel from its bridge. Bridge events without a bridge
+// id in their extra field are ignored.
+func BridgeMembership(events []Event) []BridgeSnapshot {
+	var snapshots []BridgeSnapshot
+	members := make(map[string][]string)
+	bridgeOf := make(map[string]string)
+	leave := func(channel string) bool {
+		id, ok := bridgeOf[channel]
+		if !ok {
+			return false
+		}
+		delete(bridgeOf, channel)
+		m := members[id]
+		for i := range m {
+			if m[i] == channel {
+				m = append(m[:i:i], m[i+1:]...)
+				break
+			}
+		}
+		if len(m) == 0 {
+			delete(members, id)
+		} else {
+			members[id] = m
+		}
+		return true
+	}
+	var changed bool
+	for i, e := range events {
+		switch e.Type {
+		case BridgeEnter:
+			x, err := ParseBridgeExtra(e.Extra)
+			if err != nil || x.BridgeID == "" || bridgeOf[e.ChanName] == x.BridgeID {
+				break
+			}
+			leave(e.ChanName)
+			members[x.BridgeID] = append(members[x.BridgeID], e.ChanName)
+			bridgeOf[e.ChanName] = x.BridgeID
+			changed = true
+		case BridgeExit:
+			x, err := ParseBridgeExtra(e.Extra)
+			if err == nil && x.BridgeID != "" && bridgeOf[e.ChanName] == x.BridgeID {
+				changed = leave(e.ChanName) || changed
+			}
+		case ChanEnd:
+			changed = leave(e.ChanName) || changed
+		}
+		if changed && (i == len(events)-1 || !events[i+1].Time.Equal(e.Time)) {
+			snapshots = append(snapshots, BridgeSnapshot{Time: e.Time, Bridges: copyMembers(members)})
+			changed = false
+		}
+	}
+	return snapshots
+}
+
+func copyMembers(members map[string][]string) map[string][]string {
+	m := make(map[string][]string, len(members))
+	for id, channels := range members {
+		m[id] = append([]string(nil), channels...)
+	}
+	return m
+}
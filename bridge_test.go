@@ -0,0 +1,57 @@
+package cel_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VoIPGRID/cel"
+	"github.com/matryer/is"
+)
+
+func TestParseBridgeExtra(t *testing.T) {
+	is := is.NewRelaxed(t)
+	x, err := cel.ParseBridgeExtra(`{"bridge_id":"b1","bridge_technology":"simple_bridge"}`)
+	is.NoErr(err)
+	is.Equal(x, cel.BridgeExtra{BridgeID: "b1", BridgeTechnology: "simple_bridge"})
+	_, err = cel.ParseBridgeExtra("")
+	is.True(strings.HasPrefix(err.Error(), "bad bridge extra: "))
+}
+
+func TestBridgeMembership(t *testing.T) {
+	is := is.NewRelaxed(t)
+	at := func(sec int64) time.Time { return time.Unix(1530794700+sec, 0) }
+	ev := func(typ cel.EventType, sec int64, channel, bridge string) cel.Event {
+		b := cel.NewEventBuilder().Type(typ).At(at(sec)).ChanName(channel)
+		if bridge != "" {
+			b.Extra(`{"bridge_id":"` + bridge + `","bridge_technology":"simple_bridge"}`)
+		}
+		return b.Event()
+	}
+	events := []cel.Event{
+		ev(cel.ChanStart, 0, "SIP/a", ""),
+		ev(cel.BridgeEnter, 5, "SIP/a", "conf"),
+		ev(cel.BridgeEnter, 5, "SIP/b", "conf"),
+		ev(cel.BridgeEnter, 6, "SIP/c", "conf"),
+		ev(cel.BridgeEnter, 7, "SIP/x", "other"), // overlapping bridge
+		ev(cel.BridgeExit, 8, "SIP/b", "conf"),
+		ev(cel.BridgeEnter, 8, "SIP/b", "other"),
+		ev(cel.BridgeEnter, 9, "SIP/c", "other"), // moves without exit
+		ev(cel.BridgeExit, 10, "SIP/c", "conf"),  // not in conf anymore
+		ev(cel.BridgeEnter, 10, "SIP/d", ""),     // no bridge id
+		ev(cel.ChanEnd, 11, "SIP/a", ""),         // without exit
+		ev(cel.BridgeExit, 12, "SIP/x", "other"),
+		ev(cel.BridgeExit, 12, "SIP/b", "other"),
+		ev(cel.BridgeExit, 12, "SIP/c", "other"),
+	}
+	is.Equal(cel.BridgeMembership(events), []cel.BridgeSnapshot{
+		{at(5), map[string][]string{"conf": {"SIP/a", "SIP/b"}}},
+		{at(6), map[string][]string{"conf": {"SIP/a", "SIP/b", "SIP/c"}}},
+		{at(7), map[string][]string{"conf": {"SIP/a", "SIP/b", "SIP/c"}, "other": {"SIP/x"}}},
+		{at(8), map[string][]string{"conf": {"SIP/a", "SIP/c"}, "other": {"SIP/x", "SIP/b"}}},
+		{at(9), map[string][]string{"conf": {"SIP/a"}, "other": {"SIP/x", "SIP/b", "SIP/c"}}},
+		{at(11), map[string][]string{"other": {"SIP/x", "SIP/b", "SIP/c"}}},
+		{at(12), map[string][]string{}},
+	})
+	is.Equal(len(cel.BridgeMembership(nil)), 0)
+}
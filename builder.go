@@ -0,0 +1,123 @@
+package cel
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// An EventBuilder constructs events, for use as fixtures in tests. Its setters
+// return the builder, so calls can be chained, as in
+//
+//	line := cel.NewEventBuilder().Type(cel.Answer).UniqueID("1.2").LinkedID("1.1").Line()
+//
+// Fields that are not set have plausible defaults.
+type EventBuilder struct {
+	e Event
+}
+
+// NewEventBuilder returns a builder for a CHAN_START event at 2018-07-05
+// 12:45:00 UTC, for channel SIP/1001-00000001 in context "default", with
+// AMAFlagsDocumentation. Unless they are set, the uniqueid is derived
+// from the time of the event (as Asterisk does), and the linkedid is the
+// uniqueid.
+func NewEventBuilder() *EventBuilder {
+	return &EventBuilder{Event{
+		Type:     ChanStart,
+		Time:     time.Unix(1530794700, 0),
+		Context:  "default",
+		ChanName: "SIP/1001-00000001",
+		AMAFlags: AMAFlagsDocumentation,
+	}}
+}
+
+// Type sets the event type.
+func (b *EventBuilder) Type(t EventType) *EventBuilder { b.e.Type = t; return b }
+
+// At sets the time of the event.
+func (b *EventBuilder) At(t time.Time) *EventBuilder { b.e.Time = t; return b }
+
+// CIDName sets the caller ID name.
+func (b *EventBuilder) CIDName(s string) *EventBuilder { b.e.CIDName = s; return b }
+
+// CIDNum sets the caller ID number.
+func (b *EventBuilder) CIDNum(s string) *EventBuilder { b.e.CIDNum = s; return b }
+
+// CIDANI sets the caller ID ANI.
+func (b *EventBuilder) CIDANI(s string) *EventBuilder { b.e.CIDANI = s; return b }
+
+// CIDRDNIS sets the caller ID RDNIS.
+func (b *EventBuilder) CIDRDNIS(s string) *EventBuilder { b.e.CIDRDNIS = s; return b }
+
+// CIDDNID sets the caller ID DNID.
+func (b *EventBuilder) CIDDNID(s string) *EventBuilder { b.e.CIDDNID = s; return b }
+
+// Exten sets the extension.
+func (b *EventBuilder) Exten(s string) *EventBuilder { b.e.Exten = s; return b }
+
+// Context sets the dialplan context.
+func (b *EventBuilder) Context(s string) *EventBuilder { b.e.Context = s; return b }
+
+// ChanName sets the channel name.
+func (b *EventBuilder) ChanName(s string) *EventBuilder { b.e.ChanName = s; return b }
+
+// AppName sets the dialplan application.
+func (b *EventBuilder) AppName(s string) *EventBuilder { b.e.AppName = s; return b }
+
+// AppData sets the arguments of the dialplan application.
+func (b *EventBuilder) AppData(s string) *EventBuilder { b.e.AppData = s; return b }
+
+// AMAFlags sets the AMA flags.
+func (b *EventBuilder) AMAFlags(f AMAFlags) *EventBuilder { b.e.AMAFlags = f; return b }
+
+// AccountCode sets the account code.
+func (b *EventBuilder) AccountCode(s string) *EventBuilder { b.e.AccountCode = s; return b }
+
+// UniqueID sets the uniqueid.
+func (b *EventBuilder) UniqueID(s string) *EventBuilder { b.e.UniqueID = s; return b }
+
+// LinkedID sets the linkedid.
+func (b *EventBuilder) LinkedID(s string) *EventBuilder { b.e.LinkedID = s; return b }
+
+// Peer sets the name of the bridged peer channel.
+func (b *EventBuilder) Peer(s string) *EventBuilder { b.e.Peer = s; return b }
+
+// UserField sets the userfield.
+func (b *EventBuilder) UserField(s string) *EventBuilder { b.e.UserField = s; return b }
+
+// UserDefType sets the type of a user defined event.
+func (b *EventBuilder) UserDefType(s string) *EventBuilder { b.e.UserDefType = s; return b }
+
+// Extra sets the extra field.
+func (b *EventBuilder) Extra(s string) *EventBuilder { b.e.Extra = s; return b }
+
+// Event returns the event.
+func (b *EventBuilder) Event() Event {
+	e := b.e
+	if e.UniqueID == "" {
+		e.UniqueID = fmt.Sprintf("%d.1", e.Time.Unix())
+	}
+	if e.LinkedID == "" {
+		e.LinkedID = e.UniqueID
+	}
+	return e
+}
+
+// Record returns the event as a record, as written by MarshalEvent.
+func (b *EventBuilder) Record() []string {
+	record, err := MarshalEvent(b.Event())
+	if err != nil {
+		panic(err) // All fields of Event can be marshaled.
+	}
+	return record
+}
+
+// Line returns the event as a line in the format of Master.csv, in which
+// every value is quoted, without line terminator.
+func (b *EventBuilder) Line() string {
+	record := b.Record()
+	for i, s := range record {
+		record[i] = `"` + strings.Replace(s, `"`, `""`, -1) + `"`
+	}
+	return strings.Join(record, ",")
+}
@@ -0,0 +1,39 @@
+package cel_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VoIPGRID/cel"
+	"github.com/matryer/is"
+)
+
+func TestEventBuilder(t *testing.T) {
+	is := is.NewRelaxed(t)
+	e := cel.NewEventBuilder().Event()
+	is.Equal(e.Type, cel.ChanStart)
+	is.Equal(e.Time, time.Unix(1530794700, 0))
+	is.Equal(e.UniqueID, "1530794700.1")
+	is.Equal(e.LinkedID, e.UniqueID)
+
+	b := cel.NewEventBuilder().
+		Type(cel.Hangup).
+		At(time.Unix(1530794730, 250000000)).
+		CIDNum("1001").
+		UniqueID("1530794700.2").
+		LinkedID("1530794700.1").
+		AppData("SIP/2001,30").
+		Extra(`{"hangupcause":16,"dialstatus":"ANSWER"}`)
+	is.Equal(b.Line(), `"HANGUP","1530794730.250000","","1001","","","","","default","SIP/1001-00000001","","SIP/2001,30","3","","1530794700.2","1530794700.1","","","","{""hangupcause"":16,""dialstatus"":""ANSWER""}"`)
+
+	// Built events round-trip through the decoder.
+	in := strings.Join([]string{cel.NewEventBuilder().Line(), b.Line()}, "\n")
+	var events []cel.Event
+	is.NoErr(cel.NewDecoder(strings.NewReader(in)).DecodeAll(&events))
+	is.Equal(events, []cel.Event{cel.NewEventBuilder().Event(), b.Event()})
+
+	var fromRecord cel.Event
+	is.NoErr(cel.UnmarshalEvent(b.Record(), &fromRecord))
+	is.Equal(fromRecord, b.Event())
+}
@@ -0,0 +1,286 @@
+package cel
+
+import (
+	"io"
+	"strings"
+	"time"
+)
+
+// A Call holds the events of all channels that share a linkedid.
+type Call struct {
+	LinkedID string
+	Events   []Event
+}
+
+// GroupByLinkedID groups events into calls by their linkedid. Calls are
+// returned in the order of their first event, and the events of each call
+// keep the order they have in events.
+func GroupByLinkedID(events []Event) []*Call {
+	var calls []*Call
+	byID := make(map[string]*Call)
+	for _, e := range events {
+		c, ok := byID[e.LinkedID]
+		if !ok {
+			c = &Call{LinkedID: e.LinkedID}
+			byID[e.LinkedID] = c
+			calls = append(calls, c)
+		}
+		c.Events = append(c.Events, e)
+	}
+	return calls
+}
+
+// OriginatingChannel returns the name of the channel that started the call.
+//
+// The appname of the events is used first: the originating channel is the
+// first channel to run the Dial application, or, for calls created by
+// Originate without Dial, the channel that Originate called first, which
+// Asterisk logs with the AppDial2 appname. Local channels are skipped, as
+// they only relay the call. Otherwise, CHAN_START order is used: Asterisk
+// sets the linkedid of a call to the uniqueid of its first channel, so that
+// channel is returned if it has a CHAN_START event, or else the channel of
+// the first CHAN_START event. Returns an empty string if none of these
+// apply.
+func (c *Call) OriginatingChannel() string {
+	var originated, linked, first string
+	for _, e := range c.Events {
+		if !strings.HasPrefix(e.ChanName, "Local/") {
+			switch e.AppName {
+			case "Dial":
+				return e.ChanName
+			case "AppDial2":
+				if originated == "" {
+					originated = e.ChanName
+				}
+			}
+		}
+		if e.Type != ChanStart {
+			continue
+		}
+		if e.UniqueID == c.LinkedID && linked == "" {
+			linked = e.ChanName
+		}
+		if first == "" {
+			first = e.ChanName
+		}
+	}
+	for _, name := range []string{originated, linked, first} {
+		if name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// AnsweringChannel returns the name of the channel that answered the call:
+// the first channel, other than the originating channel, to have an ANSWER
+// event with the AppDial appname, which Asterisk logs for channels that
+// answer a Dial. If there is none, the first such channel to have any ANSWER
+// event is returned. Local channels are skipped, since they answer as soon
+// as they are dialed. Returns an empty string if the call was not answered,
+// or if this cannot be determined.
+func (c *Call) AnsweringChannel() string {
+	orig := c.OriginatingChannel()
+	if orig == "" {
+		return ""
+	}
+	var first string
+	for _, e := range c.Events {
+		if e.Type != Answer || e.ChanName == orig || strings.HasPrefix(e.ChanName, "Local/") {
+			continue
+		}
+		if e.AppName == "AppDial" {
+			return e.ChanName
+		}
+		if first == "" {
+			first = e.ChanName
+		}
+	}
+	return first
+}
+
+// EventCounts returns the histogram of the call's events, as returned by
+// EventTypeHistogram.
+func (c *Call) EventCounts() map[string]int {
+	return EventTypeHistogram(c.Events)
+}
+
+// Calls decodes events (see Event) from r and sends each call on the first
+// returned channel once it is complete, without reading all of r first.
+//
+// A call is complete when its LINKEDID_END event is read. If idleTimeout is
+// not zero, a call is also sent, incomplete, when an event is read that is
+// more than idleTimeout later than the last event of that call. This bounds
+// the number of calls kept in memory when LINKEDID_END events are missing.
+// Time is measured using the times of the events, not the wall clock, so
+// results do not depend on how fast r is read. At the end of the input, all
+// remaining incomplete calls are sent in the order of their first event.
+//
+// Calls are sent in the order in which they are completed. Events that are
+// read after their call was sent start a new Call with the same linkedid.
+//
+// Decoding stops at the first error, which is sent on the second channel;
+// calls that are incomplete at that point are not sent. Both channels are
+// closed when decoding stops.
+func Calls(r io.Reader, idleTimeout time.Duration, opts ...Option) (<-chan *Call, <-chan error) {
+	out := make(chan *Call)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(out)
+		defer close(errs)
+		dec := NewDecoder(r, opts...)
+		open := newOpenCalls()
+		for {
+			var e Event
+			err := dec.Decode(&e)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				errs <- err
+				return
+			}
+			c := open.add(e)
+			if e.Type == LinkedIDEnd {
+				open.remove(c)
+				out <- c
+			}
+			if idleTimeout == 0 {
+				continue
+			}
+			for _, idle := range open.idle(e.Time, idleTimeout) {
+				out <- idle
+			}
+		}
+		for _, c := range open.calls {
+			out <- c
+		}
+	}()
+	return out, errs
+}
+
+// JoinByLinkedID decodes events from a and b, which log the legs of calls on
+// different servers, and sends calls that combine the events of both on the
+// first returned channel. The events of each input must be in time order;
+// the events of each call are merged in time order, with those of a first
+// for equal times.
+//
+// As each server may log a LINKEDID_END event for its own part of a call, a
+// call is not complete until no events for it have been read from either
+// input for window, measured using the times of the events (as for Calls).
+// The window trades latency for completeness: each call is held for at least
+// window after its last event, and events that arrive later than that start
+// a new Call with the same linkedid. The clocks of the servers should differ
+// by much less than window. At the end of both inputs, all remaining calls
+// are sent in the order of their first event.
+//
+// Decoding stops at the first error of either input, which is sent on the
+// second channel; calls that are incomplete at that point are not sent. Both
+// channels are closed when decoding stops.
+func JoinByLinkedID(a, b *Decoder, window time.Duration) (<-chan *Call, <-chan error) {
+	out := make(chan *Call)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(out)
+		defer close(errs)
+		inputs := []*joinInput{{dec: a}, {dec: b}}
+		open := newOpenCalls()
+		for {
+			var next *joinInput
+			for _, in := range inputs {
+				if err := in.peek(); err != nil {
+					errs <- err
+					return
+				}
+				if in.ok && (next == nil || in.e.Time.Before(next.e.Time)) {
+					next = in
+				}
+			}
+			if next == nil {
+				break
+			}
+			e := next.e
+			next.ok = false
+			for _, idle := range open.idle(e.Time, window) {
+				out <- idle
+			}
+			open.add(e)
+		}
+		for _, c := range open.calls {
+			out <- c
+		}
+	}()
+	return out, errs
+}
+
+// A joinInput holds the next event of an input of JoinByLinkedID.
+type joinInput struct {
+	dec *Decoder
+	e   Event
+	ok  bool // Whether e holds the next event.
+	eof bool
+}
+
+// peek makes sure that in.e holds the next event, unless the input ended.
+func (in *joinInput) peek() error {
+	if in.ok || in.eof {
+		return nil
+	}
+	in.e = Event{}
+	err := in.dec.Decode(&in.e)
+	if err == io.EOF {
+		in.eof = true
+		return nil
+	}
+	in.ok = err == nil
+	return err
+}
+
+// openCalls holds the calls that are being grouped, in the order of their
+// first event.
+type openCalls struct {
+	calls []*Call
+	byID  map[string]*Call
+}
+
+func newOpenCalls() *openCalls {
+	return &openCalls{byID: make(map[string]*Call)}
+}
+
+// add adds e to the open call with its linkedid, or to a new call, and
+// returns the call.
+func (o *openCalls) add(e Event) *Call {
+	c, ok := o.byID[e.LinkedID]
+	if !ok {
+		c = &Call{LinkedID: e.LinkedID}
+		o.byID[e.LinkedID] = c
+		o.calls = append(o.calls, c)
+	}
+	c.Events = append(c.Events, e)
+	return c
+}
+
+func (o *openCalls) remove(c *Call) {
+	delete(o.byID, c.LinkedID)
+	for i := range o.calls {
+		if o.calls[i] == c {
+			o.calls = append(o.calls[:i], o.calls[i+1:]...)
+			break
+		}
+	}
+}
+
+// idle removes and returns the calls whose last event is more than timeout
+// before now.
+func (o *openCalls) idle(now time.Time, timeout time.Duration) []*Call {
+	var idle []*Call
+	for i := 0; i < len(o.calls); i++ {
+		c := o.calls[i]
+		if now.Sub(c.Events[len(c.Events)-1].Time) > timeout {
+			o.remove(c)
+			i--
+			idle = append(idle, c)
+		}
+	}
+	return idle
+}
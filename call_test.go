@@ -0,0 +1,209 @@
+package cel_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VoIPGRID/cel"
+	"github.com/matryer/is"
+)
+
+func TestGroupByLinkedID(t *testing.T) {
+	is := is.NewRelaxed(t)
+	events := []cel.Event{
+		{Type: cel.ChanStart, UniqueID: "1.1", LinkedID: "1.1"},
+		{Type: cel.ChanStart, UniqueID: "2.1", LinkedID: "2.1"},
+		{Type: cel.ChanStart, UniqueID: "1.2", LinkedID: "1.1"},
+		{Type: cel.ChanEnd, UniqueID: "2.1", LinkedID: "2.1"},
+	}
+	calls := cel.GroupByLinkedID(events)
+	is.Equal(calls, []*cel.Call{
+		{LinkedID: "1.1", Events: []cel.Event{events[0], events[2]}},
+		{LinkedID: "2.1", Events: []cel.Event{events[1], events[3]}},
+	})
+}
+
+func TestCallChannels(t *testing.T) {
+	ev := func(typ cel.EventType, chanName, uniqueID, appName string) cel.Event {
+		return cel.Event{Type: typ, ChanName: chanName, UniqueID: uniqueID, LinkedID: "1.1", AppName: appName}
+	}
+	cases := []struct {
+		name        string
+		events      []cel.Event
+		orig, answd string
+	}{
+		{
+			"originate",
+			[]cel.Event{
+				ev(cel.ChanStart, "SIP/1001-01", "1.1", ""),
+				ev(cel.Answer, "SIP/1001-01", "1.1", "AppDial2"),
+				ev(cel.AppStart, "SIP/1001-01", "1.1", "Dial"),
+				ev(cel.ChanStart, "SIP/2001-02", "1.2", ""),
+				ev(cel.Answer, "SIP/2001-02", "1.2", "AppDial"),
+				ev(cel.BridgeEnter, "SIP/1001-01", "1.1", "Dial"),
+			},
+			"SIP/1001-01", "SIP/2001-02",
+		},
+		{
+			"inbound via local channel",
+			[]cel.Event{
+				ev(cel.ChanStart, "SIP/trunk-01", "1.1", ""),
+				ev(cel.AppStart, "SIP/trunk-01", "1.1", "Dial"),
+				ev(cel.ChanStart, "Local/2001@users-01;1", "1.2", ""),
+				ev(cel.ChanStart, "Local/2001@users-01;2", "1.3", ""),
+				ev(cel.Answer, "Local/2001@users-01;2", "1.3", ""),
+				ev(cel.ChanStart, "SIP/2001-02", "1.4", ""),
+				ev(cel.ChanStart, "SIP/2002-03", "1.5", ""),
+				ev(cel.Answer, "SIP/2002-03", "1.5", "AppDial"),
+				ev(cel.Answer, "SIP/trunk-01", "1.1", "Dial"),
+			},
+			"SIP/trunk-01", "SIP/2002-03",
+		},
+		{
+			"unanswered",
+			[]cel.Event{
+				ev(cel.ChanStart, "SIP/trunk-01", "1.1", ""),
+				ev(cel.ChanStart, "SIP/2001-02", "1.2", ""),
+				ev(cel.Hangup, "SIP/2001-02", "1.2", "AppDial"),
+			},
+			"SIP/trunk-01", "",
+		},
+		{
+			"dialing channel without CHAN_START",
+			[]cel.Event{
+				ev(cel.AppStart, "SIP/1001-01", "1.1", "Dial"),
+				ev(cel.ChanStart, "SIP/2001-02", "1.2", ""),
+				ev(cel.Answer, "SIP/2001-02", "1.2", "AppDial"),
+			},
+			"SIP/1001-01", "SIP/2001-02",
+		},
+		{
+			"originate without dial",
+			[]cel.Event{
+				ev(cel.ChanStart, "Local/2001@users-01;1", "1.1", ""),
+				ev(cel.ChanStart, "SIP/2001-02", "1.3", ""),
+				ev(cel.Answer, "SIP/2001-02", "1.3", "AppDial2"),
+				ev(cel.ChanStart, "SIP/3001-03", "1.4", ""),
+				ev(cel.Answer, "SIP/3001-03", "1.4", "AppQueue"),
+			},
+			"SIP/2001-02", "SIP/3001-03",
+		},
+		{
+			"answered outside dial first",
+			[]cel.Event{
+				ev(cel.ChanStart, "SIP/trunk-01", "1.1", ""),
+				ev(cel.ChanStart, "SIP/3001-03", "1.3", ""),
+				ev(cel.Answer, "SIP/3001-03", "1.3", "ChanSpy"),
+				ev(cel.AppStart, "SIP/trunk-01", "1.1", "Dial"),
+				ev(cel.ChanStart, "SIP/2001-02", "1.2", ""),
+				ev(cel.Answer, "SIP/2001-02", "1.2", "AppDial"),
+			},
+			"SIP/trunk-01", "SIP/2001-02",
+		},
+		{
+			"without CHAN_START",
+			[]cel.Event{
+				ev(cel.Answer, "SIP/2001-02", "1.2", "AppDial"),
+			},
+			"", "",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			is := is.NewRelaxed(t)
+			call := &cel.Call{LinkedID: "1.1", Events: c.events}
+			is.Equal(call.OriginatingChannel(), c.orig) // originating channel
+			is.Equal(call.AnsweringChannel(), c.answd)  // answering channel
+		})
+	}
+}
+
+// celLine returns a line in the stock Master.csv layout.
+func celLine(typ cel.EventType, sec int, uniqueID, linkedID string) string {
+	record := make([]string, 20)
+	record[0] = string(typ)
+	record[1] = fmt.Sprintf("%d.000000", 1530794700+sec)
+	record[9] = "SIP/" + uniqueID
+	record[14] = uniqueID
+	record[15] = linkedID
+	return `"` + strings.Join(record, `","`) + `"` + "\n"
+}
+
+func TestCalls(t *testing.T) {
+	is := is.NewRelaxed(t)
+	in := celLine(cel.ChanStart, 0, "1.1", "1.1") +
+		celLine(cel.ChanStart, 1, "2.1", "2.1") +
+		celLine(cel.ChanStart, 2, "3.1", "3.1") +
+		celLine(cel.ChanStart, 3, "1.2", "1.1") +
+		celLine(cel.ChanEnd, 4, "2.1", "2.1") +
+		celLine(cel.LinkedIDEnd, 5, "2.1", "2.1") +
+		celLine(cel.ChanEnd, 60, "1.2", "1.1") +
+		celLine(cel.ChanStart, 61, "4.1", "4.1") +
+		celLine(cel.LinkedIDEnd, 62, "1.1", "1.1")
+
+	collect := func(idle time.Duration) (ids []string, lens []int) {
+		calls, errs := cel.Calls(strings.NewReader(in), idle)
+		for c := range calls {
+			ids = append(ids, c.LinkedID)
+			lens = append(lens, len(c.Events))
+		}
+		is.NoErr(<-errs)
+		return ids, lens
+	}
+
+	ids, lens := collect(0)
+	is.Equal(ids, []string{"2.1", "1.1", "3.1", "4.1"})
+	is.Equal(lens, []int{3, 4, 1, 1})
+
+	ids, lens = collect(30 * time.Second)
+	is.Equal(ids, []string{"2.1", "3.1", "1.1", "4.1"})
+	is.Equal(lens, []int{3, 1, 4, 1})
+
+	bad := strings.Replace(celLine(cel.ChanEnd, 1, "1.1", "1.1"), "1530794701.000000", "x", 1)
+	calls, errs := cel.Calls(strings.NewReader(celLine(cel.ChanStart, 0, "1.1", "1.1")+bad), 0)
+	for range calls {
+		t.Error("unexpected call")
+	}
+	is.True(strings.HasPrefix(fmt.Sprint(<-errs), `line 2: failed to map field Time: unable to convert field value "x"`))
+}
+
+func TestJoinByLinkedID(t *testing.T) {
+	is := is.NewRelaxed(t)
+	a := celLine(cel.ChanStart, 0, "1.1", "1.1") +
+		celLine(cel.ChanStart, 1, "2.1", "2.1") +
+		celLine(cel.ChanEnd, 10, "1.1", "1.1") +
+		celLine(cel.LinkedIDEnd, 10, "1.1", "1.1") +
+		celLine(cel.LinkedIDEnd, 12, "2.1", "2.1")
+	b := celLine(cel.ChanStart, 2, "9.1", "1.1") +
+		celLine(cel.ChanEnd, 11, "9.1", "1.1") +
+		celLine(cel.LinkedIDEnd, 11, "9.1", "1.1") +
+		celLine(cel.ChanStart, 50, "9.2", "1.1") // later than the window
+
+	calls, errs := cel.JoinByLinkedID(cel.NewDecoder(strings.NewReader(a)), cel.NewDecoder(strings.NewReader(b)), 30*time.Second)
+	var ids []string
+	var got [][]string
+	for c := range calls {
+		ids = append(ids, c.LinkedID)
+		var uniqueIDs []string
+		for _, e := range c.Events {
+			uniqueIDs = append(uniqueIDs, e.UniqueID)
+		}
+		got = append(got, uniqueIDs)
+	}
+	is.NoErr(<-errs)
+	is.Equal(ids, []string{"1.1", "2.1", "1.1"})
+	is.Equal(got, [][]string{
+		{"1.1", "9.1", "1.1", "1.1", "9.1", "9.1"},
+		{"2.1", "2.1"},
+		{"9.2"},
+	})
+
+	bad := strings.Replace(celLine(cel.ChanEnd, 1, "9.1", "1.1"), "1530794701.000000", "x", 1)
+	calls, errs = cel.JoinByLinkedID(cel.NewDecoder(strings.NewReader(a)), cel.NewDecoder(strings.NewReader(bad)), time.Minute)
+	for range calls {
+		t.Error("unexpected call")
+	}
+	is.True(strings.HasPrefix(fmt.Sprint(<-errs), `line 1: failed to map field Time`))
+}
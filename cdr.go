@@ -0,0 +1,146 @@
+package cel
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// A CDR summarizes a call in a single record, like Asterisk's Call Detail
+// Records do.
+type CDR struct {
+	Src         string // Caller ID number of the originating channel.
+	Dst         string // Extension the originating channel started in.
+	Channel     string // The originating channel (see Call.OriginatingChannel).
+	DstChannel  string // The answering channel (see Call.AnsweringChannel).
+	UniqueID    string // Uniqueid of the originating channel.
+	LinkedID    string
+	AccountCode string // Account code of the originating channel.
+
+	Start  time.Time // Time of the first event of the call.
+	Answer time.Time // Time the answering channel answered; zero if it did not.
+	End    time.Time // Time of the last event of the call.
+
+	Duration time.Duration // End - Start.
+	BillSec  time.Duration // End - Answer, or zero if not answered.
+
+	Disposition string
+}
+
+// The dispositions of a CDR, as used by Asterisk.
+const (
+	DispositionAnswered = "ANSWERED"
+	DispositionNoAnswer = "NO ANSWER"
+	DispositionBusy     = "BUSY"
+	DispositionFailed   = "FAILED"
+)
+
+// ToCDR summarizes the call into a CDR. The call's events are expected in the
+// order they were logged. Src, Dst, UniqueID and AccountCode are taken from
+// the CHAN_START event of the originating channel, or are empty if there is
+// none.
+//
+// The disposition is DispositionAnswered if the call has an answering
+// channel. Otherwise, it is based on the dialstatus in the extra field of the
+// last HANGUP event that has one: DispositionBusy for BUSY,
+// DispositionFailed for CONGESTION and CHANUNAVAIL, and DispositionNoAnswer
+// for anything else, including calls without a dialstatus.
+func (c *Call) ToCDR() CDR {
+	cdr := CDR{LinkedID: c.LinkedID, Disposition: DispositionNoAnswer}
+	if len(c.Events) == 0 {
+		return cdr
+	}
+	cdr.Channel = c.OriginatingChannel()
+	cdr.DstChannel = c.AnsweringChannel()
+	cdr.Start = c.Events[0].Time
+	cdr.End = c.Events[len(c.Events)-1].Time
+	var dialStatus string
+	for _, e := range c.Events {
+		switch {
+		case e.Type == ChanStart && e.ChanName == cdr.Channel && cdr.UniqueID == "":
+			cdr.Src = e.CIDNum
+			cdr.Dst = e.Exten
+			cdr.UniqueID = e.UniqueID
+			cdr.AccountCode = e.AccountCode
+		case e.Type == Answer && e.ChanName == cdr.DstChannel && cdr.Answer.IsZero():
+			cdr.Answer = e.Time
+		case e.Type == Hangup:
+			var extra struct {
+				DialStatus string `json:"dialstatus"`
+			}
+			if json.Unmarshal([]byte(e.Extra), &extra) == nil && extra.DialStatus != "" {
+				dialStatus = extra.DialStatus
+			}
+		}
+	}
+	cdr.Duration = cdr.End.Sub(cdr.Start)
+	switch {
+	case cdr.DstChannel != "":
+		cdr.Disposition = DispositionAnswered
+		cdr.BillSec = cdr.End.Sub(cdr.Answer)
+	case dialStatus == "BUSY":
+		cdr.Disposition = DispositionBusy
+	case dialStatus == "CONGESTION" || dialStatus == "CHANUNAVAIL":
+		cdr.Disposition = DispositionFailed
+	}
+	return cdr
+}
+
+// A CDRRecord is a row of Asterisk's CDR, from any source, as far as needed
+// by CorrelateCDR.
+type CDRRecord interface {
+	// CDRIDs returns the uniqueid and the linkedid fields of the row. The
+	// linkedid may be empty, for sources that do not log it.
+	CDRIDs() (uniqueID, linkedID string)
+}
+
+// CDRIDs implements CDRRecord.
+func (c CDR) CDRIDs() (uniqueID, linkedID string) {
+	return c.UniqueID, c.LinkedID
+}
+
+// A Correlation holds a call and the CDR rows that belong to it.
+type Correlation struct {
+	Call *Call // Nil for CDR rows without a matching call.
+	CDRs []CDRRecord
+}
+
+// CorrelateCDR matches the CDR rows in cdrs to the calls they belong to. The
+// result is keyed by the linkedid of the calls, and includes calls without
+// any rows. Rows that match no call are keyed by their linkedid, or their
+// uniqueid if they have none, with a nil Call. Rows keep their order.
+//
+// The uniqueid of a CDR row is that of its first party channel, so a row
+// belongs to the call that has events for that channel. A channel can have
+// events in more than one call, as linkedids change when calls are
+// transferred; the first call with events for the channel is used. Rows
+// whose channel is not in any call are matched to a call by their linkedid.
+func CorrelateCDR(calls []*Call, cdrs []CDRRecord) map[string]Correlation {
+	m := make(map[string]Correlation, len(calls))
+	byChannel := make(map[string]*Call)
+	byID := make(map[string]*Call, len(calls))
+	for _, c := range calls {
+		m[c.LinkedID] = Correlation{Call: c}
+		byID[c.LinkedID] = c
+		for _, e := range c.Events {
+			if _, ok := byChannel[e.UniqueID]; !ok {
+				byChannel[e.UniqueID] = c
+			}
+		}
+	}
+	for _, cdr := range cdrs {
+		uniqueID, linkedID := cdr.CDRIDs()
+		key := linkedID
+		if key == "" {
+			key = uniqueID
+		}
+		if c, ok := byChannel[uniqueID]; ok {
+			key = c.LinkedID
+		} else if c, ok := byID[linkedID]; ok {
+			key = c.LinkedID
+		}
+		corr := m[key]
+		corr.CDRs = append(corr.CDRs, cdr)
+		m[key] = corr
+	}
+	return m
+}
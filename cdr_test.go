@@ -0,0 +1,120 @@
+package cel_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/VoIPGRID/cel"
+	"github.com/matryer/is"
+)
+
+func TestCallToCDR(t *testing.T) {
+	at := func(sec int64) time.Time { return time.Unix(1530794700+sec, 0) }
+	start := cel.Event{Type: cel.ChanStart, Time: at(0), ChanName: "SIP/1001-01", UniqueID: "1.1", LinkedID: "1.1", CIDNum: "1001", Exten: "2001", AccountCode: "acc1"}
+	ev := func(typ cel.EventType, sec int64, chanName, uniqueID, extra string) cel.Event {
+		return cel.Event{Type: typ, Time: at(sec), ChanName: chanName, UniqueID: uniqueID, LinkedID: "1.1", Extra: extra}
+	}
+	cases := []struct {
+		name   string
+		events []cel.Event
+		want   cel.CDR
+	}{
+		{
+			"answered",
+			[]cel.Event{
+				start,
+				ev(cel.ChanStart, 1, "SIP/2001-02", "1.2", ""),
+				ev(cel.Answer, 5, "SIP/2001-02", "1.2", ""),
+				ev(cel.Answer, 5, "SIP/1001-01", "1.1", ""),
+				ev(cel.Hangup, 35, "SIP/2001-02", "1.2", `{"hangupcause":16,"dialstatus":""}`),
+				ev(cel.Hangup, 35, "SIP/1001-01", "1.1", `{"hangupcause":16,"dialstatus":"ANSWER"}`),
+				ev(cel.LinkedIDEnd, 36, "SIP/1001-01", "1.1", ""),
+			},
+			cel.CDR{
+				Src: "1001", Dst: "2001", Channel: "SIP/1001-01", DstChannel: "SIP/2001-02",
+				UniqueID: "1.1", LinkedID: "1.1", AccountCode: "acc1",
+				Start: at(0), Answer: at(5), End: at(36),
+				Duration: 36 * time.Second, BillSec: 31 * time.Second,
+				Disposition: cel.DispositionAnswered,
+			},
+		},
+		{
+			"busy",
+			[]cel.Event{
+				start,
+				ev(cel.ChanStart, 1, "SIP/2001-02", "1.2", ""),
+				ev(cel.Hangup, 2, "SIP/2001-02", "1.2", `{"hangupcause":17,"dialstatus":""}`),
+				ev(cel.Hangup, 3, "SIP/1001-01", "1.1", `{"hangupcause":17,"dialstatus":"BUSY"}`),
+			},
+			cel.CDR{
+				Src: "1001", Dst: "2001", Channel: "SIP/1001-01",
+				UniqueID: "1.1", LinkedID: "1.1", AccountCode: "acc1",
+				Start: at(0), End: at(3), Duration: 3 * time.Second,
+				Disposition: cel.DispositionBusy,
+			},
+		},
+		{
+			"unavailable",
+			[]cel.Event{start, ev(cel.Hangup, 1, "SIP/1001-01", "1.1", `{"dialstatus":"CHANUNAVAIL"}`)},
+			cel.CDR{
+				Src: "1001", Dst: "2001", Channel: "SIP/1001-01",
+				UniqueID: "1.1", LinkedID: "1.1", AccountCode: "acc1",
+				Start: at(0), End: at(1), Duration: time.Second,
+				Disposition: cel.DispositionFailed,
+			},
+		},
+		{
+			"in progress",
+			[]cel.Event{start, ev(cel.AppStart, 1, "SIP/1001-01", "1.1", "")},
+			cel.CDR{
+				Src: "1001", Dst: "2001", Channel: "SIP/1001-01",
+				UniqueID: "1.1", LinkedID: "1.1", AccountCode: "acc1",
+				Start: at(0), End: at(1), Duration: time.Second,
+				Disposition: cel.DispositionNoAnswer,
+			},
+		},
+		{
+			"no events",
+			nil,
+			cel.CDR{LinkedID: "1.1", Disposition: cel.DispositionNoAnswer},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			is := is.NewRelaxed(t)
+			call := &cel.Call{LinkedID: "1.1", Events: c.events}
+			is.Equal(call.ToCDR(), c.want)
+		})
+	}
+}
+
+func TestCorrelateCDR(t *testing.T) {
+	is := is.NewRelaxed(t)
+	ev := func(uniqueID, linkedID string) cel.Event {
+		return cel.NewEventBuilder().UniqueID(uniqueID).LinkedID(linkedID).Event()
+	}
+	calls := cel.GroupByLinkedID([]cel.Event{
+		ev("1.1", "1.1"),
+		ev("1.2", "1.1"),
+		ev("2.1", "2.1"),
+		ev("1.3", "1.3"),
+		ev("1.2", "1.3"), // transferred into another call
+		ev("3.1", "3.1"),
+	})
+	cdrs := []cel.CDRRecord{
+		cel.CDR{UniqueID: "1.1"},                  // no linkedid
+		cel.CDR{UniqueID: "1.2", LinkedID: "1.3"}, // first call of its channel
+		cel.CDR{UniqueID: "2.9", LinkedID: "2.1"}, // by linkedid
+		cel.CDR{UniqueID: "9.1", LinkedID: "9.1"}, // no call
+		cel.CDR{UniqueID: "9.2"},
+	}
+	got := cel.CorrelateCDR(calls, cdrs)
+	is.Equal(got, map[string]cel.Correlation{
+		"1.1": {Call: calls[0], CDRs: []cel.CDRRecord{cdrs[0], cdrs[1]}},
+		"2.1": {Call: calls[1], CDRs: []cel.CDRRecord{cdrs[2]}},
+		"1.3": {Call: calls[2]},
+		"3.1": {Call: calls[3]},
+		"9.1": {CDRs: []cel.CDRRecord{cdrs[3]}},
+		"9.2": {CDRs: []cel.CDRRecord{cdrs[4]}},
+	})
+}
@@ -0,0 +1,46 @@
+package cel
+
+import "time"
+
+// ChannelSpan describes the lifecycle of a single channel.
+type ChannelSpan struct {
+	UniqueID string
+	LinkedID string
+	ChanName string
+
+	// The times of the CHAN_START, ANSWER, HANGUP and CHAN_END events. A time
+	// is zero if the channel has no such event (yet).
+	Start  time.Time
+	Answer time.Time
+	Hangup time.Time
+	End    time.Time
+}
+
+// ChannelLifecycles returns the lifecycle of every channel in events, keyed
+// by uniqueid. Events are expected in the order they were logged. If a
+// channel has an event type more than once, the first one is used.
+func ChannelLifecycles(events []Event) map[string]ChannelSpan {
+	spans := make(map[string]ChannelSpan)
+	for _, e := range events {
+		s, ok := spans[e.UniqueID]
+		if !ok {
+			s = ChannelSpan{UniqueID: e.UniqueID, LinkedID: e.LinkedID, ChanName: e.ChanName}
+		}
+		var t *time.Time
+		switch e.Type {
+		case ChanStart:
+			t = &s.Start
+		case Answer:
+			t = &s.Answer
+		case Hangup:
+			t = &s.Hangup
+		case ChanEnd:
+			t = &s.End
+		}
+		if t != nil && t.IsZero() {
+			*t = e.Time
+		}
+		spans[e.UniqueID] = s
+	}
+	return spans
+}
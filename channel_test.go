@@ -0,0 +1,31 @@
+package cel_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/VoIPGRID/cel"
+	"github.com/matryer/is"
+)
+
+func TestChannelLifecycles(t *testing.T) {
+	is := is.NewRelaxed(t)
+	at := func(sec int64) time.Time { return time.Unix(1530794700+sec, 0) }
+	ev := func(typ cel.EventType, uniqueID string, sec int64) cel.Event {
+		return cel.Event{Type: typ, Time: at(sec), UniqueID: uniqueID, LinkedID: "1.1", ChanName: "SIP/" + uniqueID}
+	}
+	events := []cel.Event{
+		ev(cel.ChanStart, "1.1", 0),
+		ev(cel.AppStart, "1.1", 1),
+		ev(cel.ChanStart, "1.2", 2),
+		ev(cel.Answer, "1.2", 5),
+		ev(cel.Answer, "1.1", 5),
+		ev(cel.Hangup, "1.2", 30),
+		ev(cel.ChanEnd, "1.2", 30),
+		ev(cel.Hangup, "1.1", 31),
+	}
+	is.Equal(cel.ChannelLifecycles(events), map[string]cel.ChannelSpan{
+		"1.1": {UniqueID: "1.1", LinkedID: "1.1", ChanName: "SIP/1.1", Start: at(0), Answer: at(5), Hangup: at(31)},
+		"1.2": {UniqueID: "1.2", LinkedID: "1.1", ChanName: "SIP/1.2", Start: at(2), Answer: at(5), Hangup: at(30), End: at(30)},
+	})
+}
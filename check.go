@@ -0,0 +1,300 @@
+package cel
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// CheckJSONTags helps find mistakes in the structs that ",json" fields (see
+// UnmarshalEvent) decode into. It is meant to be run from tests, not at
+// runtime. v must be a pointer to a struct, and record a sample record for
+// it.
+//
+// For every ",json" field of v that holds a struct (or pointer to a struct),
+// CheckJSONTags decodes the sample column as a JSON object and returns a
+// warning for each exported field of the target struct that has no json tag
+// and does not match any key of the object. Matching is case-insensitive, as
+// in encoding/json.
+//
+// The check is a heuristic: only the top level of each target struct is
+// inspected, embedded structs are skipped, and a key that is simply absent
+// from the sample is reported the same way as a field that is missing its
+// tag. Sample columns that do not hold a JSON object are skipped.
+func CheckJSONTags(v interface{}, record []string) ([]string, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return nil, &InvalidUnmarshalError{reflect.TypeOf(v)}
+	}
+	t := rv.Elem().Type()
+	var warnings []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tagParts := strings.Split(f.Tag.Get("cel"), ",")
+		if !contains(tagParts, "json") {
+			continue
+		}
+		field, err := strconv.ParseInt(tagParts[0], 10, 0)
+		if err != nil {
+			return nil, errors.Wrapf(err, "bad tag value %q for field %v", f.Tag.Get("cel"), f.Name)
+		}
+		if field < 0 || int(field) >= len(record) {
+			return nil, fmt.Errorf("field %v: index %d out of range for sample record of length %d", f.Name, field, len(record))
+		}
+		target := f.Type
+		if target.Kind() == reflect.Ptr {
+			target = target.Elem()
+		}
+		if target.Kind() != reflect.Struct {
+			continue
+		}
+		var keys map[string]json.RawMessage
+		if json.Unmarshal([]byte(record[field]), &keys) != nil {
+			continue
+		}
+		for j := 0; j < target.NumField(); j++ {
+			tf := target.Field(j)
+			if tf.PkgPath != "" || tf.Anonymous {
+				continue
+			}
+			if _, ok := tf.Tag.Lookup("json"); ok {
+				continue
+			}
+			if !hasKeyFold(keys, tf.Name) {
+				warnings = append(warnings, fmt.Sprintf("field %v.%v has no json tag and matches no key in sample", f.Name, tf.Name))
+			}
+		}
+	}
+	return warnings, nil
+}
+
+func hasKeyFold(m map[string]json.RawMessage, key string) bool {
+	for k := range m {
+		if strings.EqualFold(k, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaSampleSize is the number of records that CompareSchema reads.
+const schemaSampleSize = 100
+
+// A SchemaReport describes how well the fields of a struct fit sample data.
+type SchemaReport struct {
+	Records int // Number of sample records.
+	Fields  []FieldReport
+}
+
+// A FieldReport describes how well a field fits the sample data.
+type FieldReport struct {
+	Field string
+	Tag   string
+	Type  reflect.Type
+
+	Matched int // Records for which the field converted.
+	Failed  int // Records for which the field failed to convert.
+	Missing int // Records too short for the field's column.
+
+	// Err is the first conversion error, if any.
+	Err error
+}
+
+// Mismatch reports whether the field failed to convert for any record.
+func (f FieldReport) Mismatch() bool {
+	return f.Failed > 0
+}
+
+// Mismatches returns the reports of the fields that failed to convert for
+// any of the sample records.
+func (r SchemaReport) Mismatches() []FieldReport {
+	var m []FieldReport
+	for _, f := range r.Fields {
+		if f.Mismatch() {
+			m = append(m, f)
+		}
+	}
+	return m
+}
+
+// CompareSchema helps check a struct against real data before using it. Like
+// CheckJSONTags, it is meant to be run from tests or during setup. v must be
+// a pointer to a struct, and r a sample of records for it.
+//
+// CompareSchema reads up to the first 100 records from r and unmarshals each
+// field of v (see UnmarshalEvent) from each of the records by itself. It
+// reports, per field, for how many records this worked, and for how many it
+// failed because the value did not convert (for instance a string column
+// that does not hold a time) or because the record was too short. Fields that
+// fail for some records may still be fine if the column is not always set,
+// so the report is a hint, not a verdict.
+func CompareSchema(v interface{}, r io.Reader) (SchemaReport, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return SchemaReport{}, &InvalidUnmarshalError{reflect.TypeOf(v)}
+	}
+	t := rv.Elem().Type()
+	var report SchemaReport
+	var fields []int
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if tag := f.Tag.Get("cel"); tag != "" && f.PkgPath == "" {
+			report.Fields = append(report.Fields, FieldReport{Field: f.Name, Tag: tag, Type: f.Type})
+			fields = append(fields, i)
+		}
+	}
+	dec := NewDecoder(r)
+	for report.Records < schemaSampleSize {
+		record, err := dec.readRecord()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return SchemaReport{}, err
+		}
+		report.Records++
+		for j, i := range fields {
+			fr := &report.Fields[j]
+			err := mapField(record, reflect.New(t).Elem().Field(i), fr.Tag, &dec.cfg)
+			switch err.(type) {
+			case nil:
+				fr.Matched++
+			case *missingColumnError:
+				fr.Missing++
+			default:
+				fr.Failed++
+				if fr.Err == nil {
+					fr.Err = errors.Wrapf(err, "line %d", dec.line)
+				}
+			}
+		}
+	}
+	return report, nil
+}
+
+// A CoverageReport describes which columns of sample data the fields of a
+// struct read.
+type CoverageReport struct {
+	Records int // Number of sample records.
+	Columns int // Number of columns of the longest sample record.
+
+	// Referenced holds the columns that the struct's tags refer to, and
+	// Unreferenced the columns of the sample that no tag refers to, both in
+	// ascending order. Referenced may include columns beyond Columns.
+	Referenced   []int
+	Unreferenced []int
+}
+
+// Complete reports whether the struct reads every column of the sample.
+func (r CoverageReport) Complete() bool {
+	return len(r.Unreferenced) == 0
+}
+
+// CoverageReport helps make sure that struct v, or pointer to struct v, reads
+// every column of d's input, so that no data is dropped silently. Like
+// CompareSchema, it is meant to be run from tests or during setup.
+//
+// CoverageReport reads up to sampleN records from d, or all remaining records
+// if sampleN is zero or less, and reports the highest number of columns they
+// have and which of those the tags of v refer to, taking column names and
+// a WithRemap table into account. Fields with ",line" refer to no column,
+// and ",optional" fields with an unresolved column name are ignored. The
+// sample records are consumed, so d is usually a Decoder for a separate
+// reader of the same data.
+func (d *Decoder) CoverageReport(v interface{}, sampleN int) (CoverageReport, error) {
+	t := reflect.TypeOf(v)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return CoverageReport{}, &InvalidUnmarshalError{reflect.TypeOf(v)}
+	}
+	var report CoverageReport
+	for sampleN <= 0 || report.Records < sampleN {
+		record, err := d.readRecord()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return CoverageReport{}, err
+		}
+		report.Records++
+		if len(record) > report.Columns {
+			report.Columns = len(record)
+		}
+	}
+	referenced, err := tagColumns(t, &d.cfg)
+	if err != nil {
+		return CoverageReport{}, err
+	}
+	seen := make(map[int]bool)
+	for _, index := range referenced {
+		if d.remap != nil {
+			if index >= len(d.remap) {
+				return CoverageReport{}, errors.Errorf("remap table has %d columns, but %v uses index %d", len(d.remap), t, index)
+			}
+			index = d.remap[index]
+		}
+		if !seen[index] {
+			seen[index] = true
+			report.Referenced = append(report.Referenced, index)
+		}
+	}
+	sort.Ints(report.Referenced)
+	for i := 0; i < report.Columns; i++ {
+		if !seen[i] {
+			report.Unreferenced = append(report.Unreferenced, i)
+		}
+	}
+	return report, nil
+}
+
+// tagColumns returns the indexes of the columns that the tags of struct type
+// t refer to, including the base columns of ",secofday=<column>" options.
+func tagColumns(t reflect.Type, c *config) ([]int, error) {
+	var columns []int
+	add := func(f reflect.StructField, tagParts []string, index string) error {
+		n, err := strconv.Atoi(index)
+		if err == nil {
+			columns = append(columns, n)
+			return nil
+		}
+		n, ok := c.column(index)
+		if ok {
+			columns = append(columns, n)
+			return nil
+		}
+		if contains(tagParts, "optional") {
+			return nil
+		}
+		return errors.Wrapf(err, "bad tag value %q for field %v", strings.Join(tagParts, ","), f.Name)
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("cel")
+		if tag == "" || f.PkgPath != "" {
+			continue
+		}
+		tagParts := strings.Split(tag, ",")
+		if isLineTag(tagParts) {
+			continue
+		}
+		for _, index := range strings.Split(tagParts[0], "+") {
+			if err := add(f, tagParts, index); err != nil {
+				return nil, err
+			}
+		}
+		if base, _ := option(tagParts, "secofday"); base != "" {
+			if err := add(f, tagParts, base); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return columns, nil
+}
@@ -0,0 +1,125 @@
+package cel_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VoIPGRID/cel"
+	"github.com/matryer/is"
+)
+
+func TestCheckJSONTags(t *testing.T) {
+	is := is.NewRelaxed(t)
+	type extra struct {
+		Tagged     string `json:"hangupsource"`
+		Dialstatus string
+		HangupCode int
+		unexported int
+	}
+	v := struct {
+		Type  string `cel:"0"`
+		Extra *extra `cel:"1,json"`
+		Other int    `cel:"0,json"`
+	}{}
+	record := []string{"42", `{"hangupsource":"","dialstatus":"ANSWER","hangupcause":16}`}
+	warnings, err := cel.CheckJSONTags(&v, record)
+	is.NoErr(err)
+	is.Equal(warnings, []string{"field Extra.HangupCode has no json tag and matches no key in sample"})
+
+	_, err = cel.CheckJSONTags(&v, record[:1])
+	is.Equal(fmt.Sprint(err), "field Extra: index 1 out of range for sample record of length 1")
+	_, err = cel.CheckJSONTags(&struct {
+		Extra extra `cel:"-1,json"`
+	}{}, record)
+	is.Equal(fmt.Sprint(err), "field Extra: index -1 out of range for sample record of length 2")
+	_, err = cel.CheckJSONTags(42, record)
+	is.Equal(fmt.Sprint(err), "cel: UnmarshalEvent(non-pointer int)")
+}
+
+func TestCompareSchema(t *testing.T) {
+	is := is.NewRelaxed(t)
+	in := strings.Join([]string{
+		cel.NewEventBuilder().Line(),
+		cel.NewEventBuilder().Type(cel.Hangup).Extra(`{"hangupcause":16}`).Line(),
+		`"CHAN_END","1530794731"`,
+	}, "\n")
+
+	report, err := cel.CompareSchema(&cel.Event{}, strings.NewReader(in))
+	is.NoErr(err)
+	is.Equal(report.Records, 3)
+	is.Equal(len(report.Fields), 21)
+	is.Equal(len(report.Mismatches()), 0) // the stock layout fits
+	is.Equal(report.Fields[1].Matched, 3)
+	is.Equal(report.Fields[2].Missing, 1)
+
+	var mistyped struct {
+		Type     cel.EventType `cel:"0"`
+		ChanName time.Time     `cel:"9"` // wrong type
+		AMAFlags int           `cel:"12"`
+		Peer     string        `cel:"peeraccount,optional"`
+	}
+	report, err = cel.CompareSchema(&mistyped, strings.NewReader(in))
+	is.NoErr(err)
+	m := report.Mismatches()
+	is.Equal(len(m), 1)
+	is.Equal(m[0].Field, "ChanName")
+	is.Equal(m[0].Failed, 2)
+	is.Equal(m[0].Missing, 1)
+	is.Equal(fmt.Sprint(m[0].Err), `line 1: unable to convert field value "SIP/1001-00000001" to time.Time: strconv.ParseInt: parsing "SIP/1001-00000001": invalid syntax`)
+	is.Equal(report.Fields[2].Matched, 2)
+
+	_, err = cel.CompareSchema(mistyped, strings.NewReader(in))
+	is.True(err != nil)
+}
+
+func TestCoverageReport(t *testing.T) {
+	is := is.NewRelaxed(t)
+	in := strings.Join([]string{
+		`"CHAN_START","1530794700","1001"`,
+		`"CHAN_END","1530794730","1001","x","y"`,
+		`"HANGUP","1530794731","1001","x","y"`,
+	}, "\n")
+
+	var full struct {
+		Type cel.EventType `cel:"0"`
+		Time time.Time     `cel:"1"`
+		Rest string        `cel:"2+3+4"`
+		Line int           `cel:",line"`
+	}
+	report, err := cel.NewDecoder(strings.NewReader(in)).CoverageReport(&full, 0)
+	is.NoErr(err)
+	is.Equal(report, cel.CoverageReport{Records: 3, Columns: 5, Referenced: []int{0, 1, 2, 3, 4}})
+	is.True(report.Complete())
+
+	var partial struct {
+		Type  cel.EventType `cel:"type"`
+		Time  time.Time     `cel:"1"`
+		Extra string        `cel:"7"`
+		Peer  string        `cel:"peer,optional"`
+	}
+	dec := cel.NewDecoder(strings.NewReader(in), cel.WithColumn("type", 0))
+	report, err = dec.CoverageReport(partial, 1)
+	is.NoErr(err)
+	is.Equal(report, cel.CoverageReport{Records: 1, Columns: 3, Referenced: []int{0, 1, 7}, Unreferenced: []int{2}})
+	is.True(!report.Complete())
+	report, err = dec.CoverageReport(&partial, 0) // the rest of the input
+	is.NoErr(err)
+	is.Equal(report.Records, 2)
+	is.Equal(report.Unreferenced, []int{2, 3, 4})
+
+	dec = cel.NewDecoder(strings.NewReader(in), cel.WithRemap([]int{4, 0}))
+	report, err = dec.CoverageReport(&struct {
+		Type string `cel:"1"`
+	}{}, 0)
+	is.NoErr(err)
+	is.Equal(report.Referenced, []int{0})
+
+	_, err = cel.NewDecoder(strings.NewReader(in)).CoverageReport(&struct {
+		Type string `cel:"type"`
+	}{}, 0)
+	is.Equal(fmt.Sprint(err), `bad tag value "type" for field Type: strconv.Atoi: parsing "type": invalid syntax`)
+	_, err = cel.NewDecoder(strings.NewReader(in)).CoverageReport("", 0)
+	is.True(err != nil)
+}
@@ -0,0 +1,61 @@
+package cel
+
+// The indexes of the columns of the stock Master.csv layout of Asterisk's
+// cel_custom module, as used by Event.
+const (
+	ColEventType = iota
+	ColEventTime
+	ColCIDName
+	ColCIDNum
+	ColCIDANI
+	ColCIDRDNIS
+	ColCIDDNID
+	ColExten
+	ColContext
+	ColChanName
+	ColAppName
+	ColAppData
+	ColAMAFlags
+	ColAccountCode
+	ColUniqueID
+	ColLinkedID
+	ColPeer
+	ColUserField
+	ColUserDefType
+	ColExtra
+)
+
+// standardColumns holds the names of the columns of the stock layout, in
+// order. The names are those that Asterisk uses for its database backends.
+var standardColumns = []string{
+	ColEventType:   "eventtype",
+	ColEventTime:   "eventtime",
+	ColCIDName:     "cid_name",
+	ColCIDNum:      "cid_num",
+	ColCIDANI:      "cid_ani",
+	ColCIDRDNIS:    "cid_rdnis",
+	ColCIDDNID:     "cid_dnid",
+	ColExten:       "exten",
+	ColContext:     "context",
+	ColChanName:    "channame",
+	ColAppName:     "appname",
+	ColAppData:     "appdata",
+	ColAMAFlags:    "amaflags",
+	ColAccountCode: "accountcode",
+	ColUniqueID:    "uniqueid",
+	ColLinkedID:    "linkedid",
+	ColPeer:        "peer",
+	ColUserField:   "userfield",
+	ColUserDefType: "userdeftype",
+	ColExtra:       "extra",
+}
+
+// StandardColumns maps the names of the columns of the stock layout (as in
+// "eventtype" or "uniqueid") to their indexes.
+var StandardColumns = func() map[string]int {
+	m := make(map[string]int, len(standardColumns))
+	for i, name := range standardColumns {
+		m[name] = i
+	}
+	return m
+}()
@@ -0,0 +1,42 @@
+package cel_test
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/VoIPGRID/cel"
+	"github.com/matryer/is"
+)
+
+func TestStandardColumns(t *testing.T) {
+	is := is.NewRelaxed(t)
+	is.Equal(len(cel.StandardColumns), 20)
+	is.Equal(cel.StandardColumns["eventtype"], cel.ColEventType)
+	is.Equal(cel.StandardColumns["uniqueid"], cel.ColUniqueID)
+	is.Equal(cel.StandardColumns["extra"], cel.ColExtra)
+	is.Equal(cel.ColExtra, 19)
+
+	// The tags of Event must agree with the constants.
+	want := map[string]int{
+		"Type": cel.ColEventType, "Time": cel.ColEventTime, "CIDName": cel.ColCIDName,
+		"CIDNum": cel.ColCIDNum, "CIDANI": cel.ColCIDANI, "CIDRDNIS": cel.ColCIDRDNIS,
+		"CIDDNID": cel.ColCIDDNID, "Exten": cel.ColExten, "Context": cel.ColContext,
+		"ChanName": cel.ColChanName, "AppName": cel.ColAppName, "AppData": cel.ColAppData,
+		"AMAFlags": cel.ColAMAFlags, "AccountCode": cel.ColAccountCode, "UniqueID": cel.ColUniqueID,
+		"LinkedID": cel.ColLinkedID, "Peer": cel.ColPeer, "UserField": cel.ColUserField,
+		"UserDefType": cel.ColUserDefType, "Extra": cel.ColExtra,
+	}
+	typ := reflect.TypeOf(cel.Event{})
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		index, err := strconv.Atoi(strings.Split(f.Tag.Get("cel"), ",")[0])
+		if err != nil {
+			continue
+		}
+		is.Equal(index, want[f.Name]) // tag index of field
+		delete(want, f.Name)
+	}
+	is.Equal(len(want), 0) // fields without tags
+}
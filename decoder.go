@@ -0,0 +1,596 @@
+package cel
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// A Decoder reads and decodes CEL records from an input stream.
+//
+// Input is read one line at a time. A line ends at the first '\n'; the
+// terminating "\n" or "\r\n" is removed before the line is split into a
+// record. The last line of the input does not need a terminating newline.
+// Empty lines are skipped. Because of this, a record may never span more than
+// one line, not even inside a quoted field.
+type Decoder struct {
+	r     *bufio.Reader
+	split func(line []byte) ([]string, error)
+	line  int
+	cfg   config
+
+	trimTrailingEmpty bool
+	utf8Policy        UTF8Policy
+	remap             []int
+	maxFieldSize      int
+	holdPartial       bool
+	header            bool // Whether the first line is still to be read as header.
+	maxRecords        int
+	partial           []byte // Unterminated line held by holdPartial.
+
+	records  int
+	raw      []byte // Line of the last record.
+	hook     func(recordNum int, d time.Duration)
+	warnings []string
+
+	// filters are applied in order to each record; records for which a
+	// filter returns false are skipped.
+	filters []func(record []string) bool
+}
+
+// An Option configures a Decoder. Options that concern the conversion of
+// values also apply to UnmarshalLine and MarshalEvent.
+type Option func(*Decoder)
+
+// WithSplitter makes the Decoder use split to turn a line into a record,
+// instead of the default CSV splitter. It is meant as an escape hatch for
+// inputs that encoding/csv cannot handle.
+//
+// The line passed to split never contains the line terminator (see Decoder),
+// and split must not retain it after returning.
+func WithSplitter(split func(line []byte) ([]string, error)) Option {
+	return func(d *Decoder) {
+		d.split = split
+	}
+}
+
+// WithDecimalComma makes the Decoder treat a comma as the decimal separator
+// when converting values to floats and durations, as used in some localized
+// exports. As the comma is also the CSV delimiter, this only works for quoted
+// values (as in "12,5"). Times are not affected. The default is a period.
+func WithDecimalComma(enabled bool) Option {
+	return func(d *Decoder) {
+		d.cfg.decimalComma = enabled
+	}
+}
+
+// WithTimeLayouts makes the Decoder parse time.Time fields using the first
+// of layouts (see time.Parse) that matches the value. Values that match none
+// of them are parsed as Unix time. Fields with a ",layout" tag option (see
+// UnmarshalEvent) only use the layout from their tag.
+func WithTimeLayouts(layouts []string) Option {
+	return func(d *Decoder) {
+		d.cfg.timeLayouts = layouts
+	}
+}
+
+// WithZoneAbbreviations makes the Decoder resolve time zone abbreviations
+// (as in "CEST") using zones, which maps each abbreviation to its offset in
+// seconds east of UTC, as for time.FixedZone. The table is used for values
+// that are parsed using a layout that includes a zone name (see
+// WithTimeLayouts and the ",layout" tag option). By itself, time.Parse only
+// knows the abbreviations of the local time zone, and assumes an offset of
+// zero for any other.
+func WithZoneAbbreviations(zones map[string]int) Option {
+	return func(d *Decoder) {
+		d.cfg.zones = zones
+	}
+}
+
+// WithEpochBase makes the Decoder interpret numeric time values as seconds
+// since base, instead of since the Unix epoch, for sources that count from
+// another origin. Fractions are added as usual. Values parsed using a layout
+// (see WithTimeLayouts) are not affected.
+func WithEpochBase(base time.Time) Option {
+	return func(d *Decoder) {
+		d.cfg.epochBase = &base
+	}
+}
+
+// WithColumn makes tags that refer to column name (see UnmarshalEvent) use
+// the column at index.
+func WithColumn(name string, index int) Option {
+	return func(d *Decoder) {
+		if d.cfg.columns == nil {
+			d.cfg.columns = make(map[string]int)
+		}
+		d.cfg.columns[name] = index
+	}
+}
+
+// WithHeader makes the Decoder read the first non-empty line of its input as
+// a header with the names of the columns, instead of as a record. Each name,
+// with surrounding white space removed, refers to its column like a name
+// given using WithColumn would; names given using WithColumn take
+// precedence, as does the first of duplicate names. See also
+// WithCaseInsensitiveHeaders.
+func WithHeader(enabled bool) Option {
+	return func(d *Decoder) {
+		d.header = enabled
+	}
+}
+
+// WithCaseInsensitiveHeaders makes tags that refer to a column name match the
+// names given using WithHeader or WithColumn regardless of case, so that
+// `cel:"eventtype"` matches EventType and EVENTTYPE as well. An exact match
+// is preferred; among names that only differ in case, any may be used.
+func WithCaseInsensitiveHeaders(enabled bool) Option {
+	return func(d *Decoder) {
+		d.cfg.foldNames = enabled
+	}
+}
+
+// WithTrimTrailingEmpty makes the Decoder drop the last field of a record if
+// it is empty and the line ends in a comma, to handle exports that terminate
+// every line with a comma.
+//
+// The decoder cannot know how many columns a record should have, so it relies
+// on quoting instead: Asterisk quotes every value, so a genuinely empty last
+// column is written as "" and is kept. With unquoted input, an empty last
+// column cannot be told apart from a trailing comma, and is dropped as well.
+func WithTrimTrailingEmpty(enabled bool) Option {
+	return func(d *Decoder) {
+		d.trimTrailingEmpty = enabled
+	}
+}
+
+// WithDecodeHook makes the Decoder call hook after decoding each record, with
+// the number of the record (starting at 1) and the time Decode spent on it,
+// including reading. The hook is also called for records that fail to
+// decode, but not when Decode returns an error while reading, such as io.EOF.
+func WithDecodeHook(hook func(recordNum int, d time.Duration)) Option {
+	return func(d *Decoder) {
+		d.hook = hook
+	}
+}
+
+// WithRemap makes the Decoder reorder each record before it is unmarshaled,
+// for sources with a different column order than the struct's tags expect.
+// Column i of the reordered record is taken from index remap[i] of the
+// source record. Decoding returns an error if a tag uses an index beyond the
+// table, or if the table refers to an index beyond the source record.
+func WithRemap(remap []int) Option {
+	return func(d *Decoder) {
+		d.remap = remap
+	}
+}
+
+// A MissingColumnPolicy determines what a Decoder does with fields whose tag
+// points to an index beyond the length of a record.
+type MissingColumnPolicy int
+
+// The missing column policies.
+const (
+	MissingColumnError    MissingColumnPolicy = iota // Return an error (the default).
+	MissingColumnZero                                // Set the field to its zero value.
+	MissingColumnZeroWarn                            // Like MissingColumnZero, and record a warning.
+)
+
+// WithMissingColumnPolicy sets what the Decoder does with fields whose tag
+// points to an index beyond the length of a record. Warnings recorded by
+// MissingColumnZeroWarn are returned by Warnings.
+func WithMissingColumnPolicy(policy MissingColumnPolicy) Option {
+	return func(d *Decoder) {
+		d.cfg.missing = policy
+	}
+}
+
+// Warnings returns the warnings recorded since the previous call to Warnings.
+func (d *Decoder) Warnings() []string {
+	w := d.warnings
+	d.warnings = nil
+	return w
+}
+
+// A UTF8Policy determines what a Decoder does with fields that are not valid
+// UTF-8.
+type UTF8Policy int
+
+// The UTF-8 policies.
+const (
+	UTF8Keep    UTF8Policy = iota // Keep fields as they are (the default).
+	UTF8Reject                    // Return an error for the record.
+	UTF8Replace                   // Replace invalid bytes with U+FFFD.
+)
+
+// WithUTF8Policy sets what the Decoder does with fields that are not valid
+// UTF-8. With UTF8Replace, each run of invalid bytes is replaced by a single
+// replacement character. The policy applies to every field of a record
+// before it is unmarshaled.
+func WithUTF8Policy(policy UTF8Policy) Option {
+	return func(d *Decoder) {
+		d.utf8Policy = policy
+	}
+}
+
+// WithMaxFieldSize makes the Decoder return an error for records with a field
+// of more than n bytes, instead of unmarshaling them, to bound the time spent
+// on a single record: converting a field, in particular with ",json", takes
+// time proportional to its size. A Decode that returns this error has
+// consumed the line, so the next Decode continues with the next line.
+//
+// The Decoder cannot interrupt a conversion that is in progress, so there is
+// no timeout as such; with a maximum field size, the worst case per record
+// is bounded by the size of the line instead. Zero (the default) means no
+// limit.
+func WithMaxFieldSize(n int) Option {
+	return func(d *Decoder) {
+		d.maxFieldSize = n
+	}
+}
+
+// WithHoldPartialLines makes the Decoder hold a last line that is not
+// terminated by a newline, instead of decoding it, for following a file that
+// is still being written: such a line may be a record that is only partially
+// written. Decode returns io.EOF instead, and when it is called again once
+// more data can be read, it continues the held line with that data.
+//
+// As a consequence, a last line without a newline is never decoded. This
+// suits the files Asterisk writes, which terminate every line.
+func WithHoldPartialLines(enabled bool) Option {
+	return func(d *Decoder) {
+		d.holdPartial = enabled
+	}
+}
+
+// WithMaxRecords makes the Decoder stop after n records, for previews and
+// samples of large inputs: Decode (and DecodeAll) then returns io.EOF without
+// reading any further. Only records that are not skipped by wrappers such as
+// FilterDecoder count, including records that fail to unmarshal. Zero (the
+// default) means no limit.
+func WithMaxRecords(n int) Option {
+	return func(d *Decoder) {
+		d.maxRecords = n
+	}
+}
+
+// NewDecoder returns a new decoder that reads from r.
+func NewDecoder(r io.Reader, opts ...Option) *Decoder {
+	d := &Decoder{
+		r:     bufio.NewReader(r),
+		split: splitCSV,
+	}
+	d.cfg.warn = func(warning string) {
+		d.warnings = append(d.warnings, fmt.Sprintf("line %d: %s", d.line, warning))
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Decode reads the next record from its input and stores it in the value
+// pointed to by v, as described in UnmarshalEvent. At the end of the input,
+// Decode returns io.EOF.
+func (d *Decoder) Decode(v interface{}) error {
+	var start time.Time
+	if d.hook != nil {
+		start = time.Now()
+	}
+	record, err := d.readRecord()
+	if err != nil {
+		return err
+	}
+	d.records++
+	err = d.unmarshal(record, v, d.line)
+	if d.hook != nil {
+		d.hook(d.records, time.Since(start))
+	}
+	return errors.Wrapf(err, "line %d", d.line)
+}
+
+// unmarshal unmarshals record, read from the given line, into v, using the
+// settings of the decoder.
+func (d *Decoder) unmarshal(record []string, v interface{}, line int) error {
+	if d.remap != nil {
+		var err error
+		if record, err = d.remapRecord(record, v); err != nil {
+			return err
+		}
+	}
+	d.cfg.line = line
+	return unmarshalEvent(record, v, &d.cfg, nil)
+}
+
+// remapRecord reorders record according to the WithRemap table, after
+// checking that the table covers all indexes that struct v uses.
+func (d *Decoder) remapRecord(record []string, v interface{}) ([]string, error) {
+	if t := reflect.TypeOf(v); t != nil && t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct {
+		if n := maxIndex(t.Elem(), &d.cfg); n >= len(d.remap) {
+			return nil, errors.Errorf("remap table has %d columns, but %v uses index %d", len(d.remap), t.Elem(), n)
+		}
+	}
+	remapped := make([]string, len(d.remap))
+	for i, src := range d.remap {
+		if src < 0 || src >= len(record) {
+			return nil, errors.Errorf("remap table maps column %d to index %d, beyond record of length %d", i, src, len(record))
+		}
+		remapped[i] = record[src]
+	}
+	return remapped, nil
+}
+
+// RawLine returns the line that the last record returned by Decode (or
+// DecodeLazy) was read from, without its line terminator, or nil if the last
+// call returned an error while reading, such as io.EOF. It is also set when
+// the record failed to unmarshal.
+//
+// The bytes are those of the input, as captured while reading, not a
+// reconstruction of the record: quoting and spacing are kept as they were,
+// so records that are passed through unchanged can be forwarded verbatim.
+// The Decoder does not reuse the slice.
+func (d *Decoder) RawLine() []byte {
+	return d.raw
+}
+
+// DecodeAll decodes all remaining records and appends them to the slice that
+// slicePtr points to. The slice may hold structs, or pointers to structs (as
+// in *[]Event or *[]*Event); in the latter case a new struct is allocated for
+// each record. DecodeAll stops at the first error, after appending the records
+// decoded before it. Reaching the end of the input is not an error.
+func (d *Decoder) DecodeAll(slicePtr interface{}) error {
+	t, err := newSliceTarget(slicePtr)
+	if err != nil {
+		return fmt.Errorf("cel: DecodeAll(%v): %v", reflect.TypeOf(slicePtr), err)
+	}
+	for {
+		v := reflect.New(t.structType)
+		err := d.Decode(v.Interface())
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		t.append(v)
+	}
+}
+
+// A sliceTarget is a pointer to a slice of structs, or of pointers to
+// structs.
+type sliceTarget struct {
+	slice      reflect.Value
+	structType reflect.Type
+	elemIsPtr  bool
+}
+
+func newSliceTarget(slicePtr interface{}) (*sliceTarget, error) {
+	sv := reflect.ValueOf(slicePtr)
+	if sv.Kind() != reflect.Ptr || sv.IsNil() || sv.Elem().Kind() != reflect.Slice {
+		return nil, errors.New("not a pointer to a slice")
+	}
+	t := &sliceTarget{slice: sv.Elem(), structType: sv.Elem().Type().Elem()}
+	if t.structType.Kind() == reflect.Ptr {
+		t.structType = t.structType.Elem()
+		t.elemIsPtr = true
+	}
+	if t.structType.Kind() != reflect.Struct {
+		return nil, errors.New("not a pointer to a slice of structs")
+	}
+	return t, nil
+}
+
+// append appends v, a pointer to a struct, to the slice.
+func (t *sliceTarget) append(v reflect.Value) {
+	if !t.elemIsPtr {
+		v = v.Elem()
+	}
+	t.slice.Set(reflect.Append(t.slice, v))
+}
+
+// readRecord reads the next non-empty line and splits it into a record.
+func (d *Decoder) readRecord() ([]string, error) {
+	d.raw = nil
+	if d.maxRecords > 0 && d.records >= d.maxRecords {
+		return nil, io.EOF
+	}
+	for {
+		line, err := d.r.ReadBytes('\n')
+		if d.holdPartial {
+			if err == io.EOF {
+				d.partial = append(d.partial, line...)
+				return nil, err
+			}
+			if len(d.partial) > 0 {
+				line = append(d.partial, line...)
+				d.partial = nil
+			}
+		}
+		if len(line) == 0 && err != nil {
+			return nil, err
+		}
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		d.line++
+		line = bytes.TrimSuffix(line, []byte("\n"))
+		line = bytes.TrimSuffix(line, []byte("\r"))
+		if len(line) == 0 {
+			continue
+		}
+		record, err := d.splitLine(line)
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", d.line)
+		}
+		if d.header {
+			d.readHeader(record)
+			continue
+		}
+		if d.keep(record) {
+			d.raw = line
+			return record, nil
+		}
+	}
+}
+
+// readHeader adds the names in header to the column names of the decoder.
+func (d *Decoder) readHeader(header []string) {
+	d.header = false
+	if d.cfg.columns == nil {
+		d.cfg.columns = make(map[string]int)
+	}
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		if _, ok := d.cfg.columns[name]; !ok && name != "" {
+			d.cfg.columns[name] = i
+		}
+	}
+}
+
+func (d *Decoder) keep(record []string) bool {
+	for _, f := range d.filters {
+		if !f(record) {
+			return false
+		}
+	}
+	return true
+}
+
+// splitLine splits line, which has no line terminator, into a record.
+func (d *Decoder) splitLine(line []byte) ([]string, error) {
+	record, err := d.split(line)
+	if err != nil {
+		return nil, err
+	}
+	if d.trimTrailingEmpty && bytes.HasSuffix(line, []byte(",")) && len(record) > 0 && record[len(record)-1] == "" {
+		record = record[:len(record)-1]
+	}
+	if d.utf8Policy != UTF8Keep {
+		for i, field := range record {
+			if utf8.ValidString(field) {
+				continue
+			}
+			if d.utf8Policy == UTF8Reject {
+				return nil, errors.Errorf("field %d: invalid UTF-8", i)
+			}
+			record[i] = strings.ToValidUTF8(field, string(utf8.RuneError))
+		}
+	}
+	if d.maxFieldSize > 0 {
+		for i, field := range record {
+			if len(field) > d.maxFieldSize {
+				return nil, errors.Errorf("field %d has %d bytes, more than the maximum of %d", i, len(field), d.maxFieldSize)
+			}
+		}
+	}
+	return record, nil
+}
+
+// UnmarshalLine splits line into a record like a Decoder created with opts
+// would, and unmarshals the record into v as described in UnmarshalEvent. A
+// trailing line terminator is ignored.
+func UnmarshalLine(line string, v interface{}, opts ...Option) error {
+	d := NewDecoder(nil, opts...)
+	line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+	if line == "" {
+		return errors.New("cel: UnmarshalLine: empty line")
+	}
+	record, err := d.splitLine([]byte(line))
+	if err != nil {
+		return err
+	}
+	return d.unmarshal(record, v, d.line)
+}
+
+// splitCSV splits a single line of CSV into its fields. Quotes are handled
+// leniently because Asterisk does not always escape them.
+func splitCSV(line []byte) ([]string, error) {
+	r := csv.NewReader(bytes.NewReader(line))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	return r.Read()
+}
+
+// DecodeLines decodes each line received from lines into a new value created
+// by v, which must return a pointer to a struct (see UnmarshalEvent). Lines
+// are split like the lines of a Decoder; empty lines are skipped.
+//
+// Decoded values are sent on the first returned channel. When a line cannot
+// be decoded, an error is sent on the second channel instead, and decoding
+// continues with the next line. Sends block until they are received, so the
+// caller must receive from both channels. Both channels are closed after lines
+// is closed and all of its lines have been handled.
+func DecodeLines(lines <-chan string, v func() interface{}) (<-chan interface{}, <-chan error) {
+	out := make(chan interface{})
+	errs := make(chan error)
+	go func() {
+		defer close(out)
+		defer close(errs)
+		var n int
+		for line := range lines {
+			n++
+			line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+			if line == "" {
+				continue
+			}
+			record, err := splitCSV([]byte(line))
+			if err != nil {
+				errs <- errors.Wrapf(err, "line %d", n)
+				continue
+			}
+			value := v()
+			if err := UnmarshalEvent(record, value); err != nil {
+				errs <- errors.Wrapf(err, "line %d", n)
+				continue
+			}
+			out <- value
+		}
+	}()
+	return out, errs
+}
+
+// NewFixedWidthDecoder returns a new decoder that reads lines of fixed-width
+// columns from r, instead of CSV. Each line is cut into columns of the given
+// widths (in bytes). Columns that lie beyond the end of a short line are
+// empty, and bytes beyond the last column are ignored.
+//
+// Padding is kept in the column values; use the ",trim" tag option (see
+// UnmarshalEvent) on fields that should have it removed.
+//
+// A width of zero results in an empty column. Negative widths are invalid:
+// decoding then returns an error for every line.
+func NewFixedWidthDecoder(r io.Reader, widths []int, opts ...Option) *Decoder {
+	return NewDecoder(r, append([]Option{WithSplitter(fixedWidthSplitter(widths))}, opts...)...)
+}
+
+func fixedWidthSplitter(widths []int) func(line []byte) ([]string, error) {
+	var err error
+	for i, w := range widths {
+		if w < 0 {
+			err = errors.Errorf("cel: NewFixedWidthDecoder: width %d of column %d is negative", w, i)
+			break
+		}
+	}
+	return func(line []byte) ([]string, error) {
+		if err != nil {
+			return nil, err
+		}
+		record := make([]string, len(widths))
+		for i, w := range widths {
+			if w > len(line) {
+				w = len(line)
+			}
+			record[i] = string(line[:w])
+			line = line[w:]
+		}
+		return record, nil
+	}
+}
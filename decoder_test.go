@@ -0,0 +1,614 @@
+package cel_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VoIPGRID/cel"
+	"github.com/matryer/is"
+)
+
+type decoderEvent struct {
+	Type    string `cel:"0"`
+	AppData string `cel:"1"`
+}
+
+func TestDecoder(t *testing.T) {
+	is := is.NewRelaxed(t)
+	in := "\"CHAN_START\",\"\"\r\n\n\"APP_START\",\"a,b\"\n\"HANGUP\",\"\""
+	dec := cel.NewDecoder(strings.NewReader(in))
+
+	var got []decoderEvent
+	for {
+		var v decoderEvent
+		err := dec.Decode(&v)
+		if err == io.EOF {
+			break
+		}
+		is.NoErr(err)
+		got = append(got, v)
+	}
+	is.Equal(got, []decoderEvent{
+		{"CHAN_START", ""},
+		{"APP_START", "a,b"},
+		{"HANGUP", ""},
+	})
+}
+
+func TestDecoderWithSplitter(t *testing.T) {
+	is := is.NewRelaxed(t)
+	var lines []string
+	split := func(line []byte) ([]string, error) {
+		lines = append(lines, string(line))
+		if bytes.HasPrefix(line, []byte("!")) {
+			return nil, fmt.Errorf("bad line")
+		}
+		return strings.Split(string(line), "|"), nil
+	}
+	dec := cel.NewDecoder(strings.NewReader("CHAN_START|a,\"b\r\n!\n"), cel.WithSplitter(split))
+
+	var v decoderEvent
+	is.NoErr(dec.Decode(&v))
+	is.Equal(v, decoderEvent{"CHAN_START", `a,"b`})
+	is.Equal(fmt.Sprint(dec.Decode(&v)), "line 2: bad line")
+	is.Equal(dec.Decode(&v), io.EOF)
+	is.Equal(lines, []string{`CHAN_START|a,"b`, "!"})
+}
+
+func TestDecodeLines(t *testing.T) {
+	is := is.NewRelaxed(t)
+	type event struct {
+		Type    string `cel:"0"`
+		AppData string `cel:"1"`
+		Number  int    `cel:"2"`
+	}
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		for _, l := range []string{`"CHAN_START","",1`, "", `"APP_START","a,b",2` + "\r\n", `"HANGUP","",x`} {
+			lines <- l
+		}
+	}()
+	out, errs := cel.DecodeLines(lines, func() interface{} { return new(event) })
+
+	var got []event
+	var gotErrs []string
+	for out != nil || errs != nil {
+		select {
+		case v, ok := <-out:
+			if !ok {
+				out = nil
+				continue
+			}
+			got = append(got, *v.(*event))
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			gotErrs = append(gotErrs, err.Error())
+		}
+	}
+	is.Equal(got, []event{{"CHAN_START", "", 1}, {"APP_START", "a,b", 2}})
+	is.Equal(len(gotErrs), 1)
+	is.True(strings.HasPrefix(gotErrs[0], "line 4: "))
+}
+
+func TestFixedWidthDecoder(t *testing.T) {
+	is := is.NewRelaxed(t)
+	type event struct {
+		Type     string    `cel:"0,trim"`
+		Time     time.Time `cel:"1,trim"`
+		ChanName string    `cel:"2"`
+		Exten    string    `cel:"3,trim"`
+	}
+	in := "CHAN_START  1530794700.000000 SIP/1001  2001\n" +
+		"HANGUP      1530794730.5      SIP/1001"
+	dec := cel.NewFixedWidthDecoder(strings.NewReader(in), []int{12, 18, 10, 4})
+
+	var v event
+	is.NoErr(dec.Decode(&v))
+	is.Equal(v, event{"CHAN_START", time.Unix(1530794700, 0), "SIP/1001  ", "2001"})
+	is.NoErr(dec.Decode(&v))
+	is.Equal(v, event{"HANGUP", time.Unix(1530794730, 500000000), "SIP/1001", ""})
+	is.Equal(dec.Decode(&v), io.EOF)
+
+	// A zero width results in an empty column.
+	var zero struct {
+		Type  string `cel:"0,trim"`
+		Empty string `cel:"1"`
+		Time  string `cel:"2"`
+	}
+	dec = cel.NewFixedWidthDecoder(strings.NewReader(in), []int{12, 0, 10})
+	is.NoErr(dec.Decode(&zero))
+	is.Equal(zero.Type, "CHAN_START")
+	is.Equal(zero.Empty, "")
+	is.Equal(zero.Time, "1530794700")
+
+	dec = cel.NewFixedWidthDecoder(strings.NewReader(in), []int{12, -1})
+	err := dec.Decode(&v)
+	is.Equal(fmt.Sprint(err), "line 1: cel: NewFixedWidthDecoder: width -1 of column 1 is negative")
+}
+
+func TestDecoderWithDecimalComma(t *testing.T) {
+	is := is.NewRelaxed(t)
+	type event struct {
+		Cost     float64       `cel:"0"`
+		Duration time.Duration `cel:"1"`
+	}
+	in := `"12,5","1,5"`
+
+	var v event
+	dec := cel.NewDecoder(strings.NewReader(in), cel.WithDecimalComma(true))
+	is.NoErr(dec.Decode(&v))
+	is.Equal(v, event{12.5, 1500 * time.Millisecond})
+
+	dec = cel.NewDecoder(strings.NewReader(in))
+	err := dec.Decode(&v)
+	is.Equal(fmt.Sprint(err), `line 1: failed to map field Cost: unable to convert field value "12,5" to float64: strconv.ParseFloat: parsing "12,5": invalid syntax`)
+}
+
+func TestDecoderWithColumn(t *testing.T) {
+	is := is.NewRelaxed(t)
+	line := `"ANSWER","1530794700.000000","","","","","","","","SIP/1001-01","","","3","acc1","1.1","1.1","","","","",`
+	cases := []struct {
+		in          string
+		opts        []cel.Option
+		local, peer string
+	}{
+		{line, nil, "acc1", ""},
+		{line + `"acc2"`, []cel.Option{cel.WithColumn("peeraccount", 20)}, "acc1", "acc2"},
+	}
+	for _, c := range cases {
+		var e cel.Event
+		is.NoErr(cel.NewDecoder(strings.NewReader(c.in), c.opts...).Decode(&e))
+		local, peer := e.BillingAccounts()
+		is.Equal(local, c.local)
+		is.Equal(peer, c.peer)
+	}
+
+	var v struct {
+		Cost string `cel:"cost"`
+	}
+	dec := cel.NewDecoder(strings.NewReader("a,b\n"), cel.WithColumn("peeraccount", 1))
+	is.Equal(fmt.Sprint(dec.Decode(&v)), `line 1: failed to map field Cost: bad tag value "cost": strconv.ParseInt: parsing "cost": invalid syntax`)
+}
+
+func TestDecoderWithHeader(t *testing.T) {
+	is := is.NewRelaxed(t)
+	type event struct {
+		Type     cel.EventType `cel:"eventtype"`
+		UniqueID string        `cel:"uniqueid"`
+		Peer     string        `cel:"peeraccount,optional"`
+	}
+	in := "\n EventType ,UNIQUEID,Extra\nCHAN_START,1.1,x\nHANGUP,1.1,y\n"
+
+	var events []event
+	dec := cel.NewDecoder(strings.NewReader(in), cel.WithHeader(true), cel.WithCaseInsensitiveHeaders(true))
+	is.NoErr(dec.DecodeAll(&events))
+	is.Equal(events, []event{{cel.ChanStart, "1.1", ""}, {cel.Hangup, "1.1", ""}})
+
+	// Without the option, names must match exactly.
+	dec = cel.NewDecoder(strings.NewReader(in), cel.WithHeader(true))
+	var v struct {
+		Type string `cel:"EventType"`
+		ID   string `cel:"uniqueid"`
+	}
+	err := dec.Decode(&v)
+	is.Equal(fmt.Sprint(err), `line 3: failed to map field ID: bad tag value "uniqueid": strconv.ParseInt: parsing "uniqueid": invalid syntax`)
+	is.Equal(v.Type, "CHAN_START")
+
+	// An exact match wins over a case-insensitive one, and WithColumn wins
+	// over the header.
+	in = "EventType,eventtype,extra\nCHAN_START,x,y\n"
+	var w struct {
+		Type  string `cel:"eventtype"`
+		Extra string `cel:"extra"`
+	}
+	dec = cel.NewDecoder(strings.NewReader(in), cel.WithHeader(true), cel.WithCaseInsensitiveHeaders(true), cel.WithColumn("extra", 0))
+	is.NoErr(dec.Decode(&w))
+	is.Equal(w.Type, "x")
+	is.Equal(w.Extra, "CHAN_START")
+}
+
+func TestDecoderWithTrimTrailingEmpty(t *testing.T) {
+	is := is.NewRelaxed(t)
+	// Joined columns report indexes out of range, which shows whether the
+	// record has a third field.
+	var v struct {
+		Type  string `cel:"0"`
+		Extra string `cel:"1+2,sep=|"`
+	}
+	in := "\"HANGUP\",\"x\",\n\"HANGUP\",\"x\",\"\"\n"
+
+	dec := cel.NewDecoder(strings.NewReader(in), cel.WithTrimTrailingEmpty(true))
+	is.Equal(fmt.Sprint(dec.Decode(&v)), "line 1: failed to map field Extra: index 2 out of range for record of length 2")
+	is.NoErr(dec.Decode(&v)) // quoted empty last column is kept
+	is.Equal(v.Extra, "x|")
+
+	dec = cel.NewDecoder(strings.NewReader(in))
+	is.NoErr(dec.Decode(&v))
+	is.Equal(v.Extra, "x|")
+	is.NoErr(dec.Decode(&v))
+}
+
+func TestDecoderStrip(t *testing.T) {
+	is := is.NewRelaxed(t)
+	var v struct {
+		Cost float64 `cel:"0,trim,strip=$€"`
+	}
+	dec := cel.NewDecoder(strings.NewReader("$0.0125\n\" € 1,20\"\n$1.2x\n"), cel.WithDecimalComma(true))
+	is.NoErr(dec.Decode(&v))
+	is.Equal(v.Cost, 0.0125)
+	is.NoErr(dec.Decode(&v))
+	is.Equal(v.Cost, 1.2)
+	err := dec.Decode(&v)
+	is.Equal(fmt.Sprint(err), `line 3: failed to map field Cost: unable to convert field value "1.2x" to float64: strconv.ParseFloat: parsing "1.2x": invalid syntax`)
+}
+
+func TestUnmarshalLine(t *testing.T) {
+	is := is.NewRelaxed(t)
+	var v struct {
+		Type    cel.EventType `cel:"0"`
+		AppName string        `cel:"1"`
+		AppData string        `cel:"2"`
+		Cost    float64       `cel:"3"`
+	}
+	is.NoErr(cel.UnmarshalLine(`"APP_START","Dial","SIP/2001,30,tT","0,5"`+"\r\n", &v, cel.WithDecimalComma(true)))
+	is.Equal(v.Type, cel.AppStart)
+	is.Equal(v.AppName, "Dial")
+	is.Equal(v.AppData, "SIP/2001,30,tT")
+	is.Equal(v.Cost, 0.5)
+
+	is.Equal(fmt.Sprint(cel.UnmarshalLine("", &v)), "cel: UnmarshalLine: empty line")
+}
+
+func TestDecoderWithTimeLayouts(t *testing.T) {
+	is := is.NewRelaxed(t)
+	var v struct {
+		Time   time.Time `cel:"0"`
+		Tagged time.Time `cel:"1,layout=02/01/2006"`
+	}
+	layouts := cel.WithTimeLayouts([]string{"2006-01-02 15:04:05", time.RFC3339})
+	in := "2018-07-05 12:45:00,05/07/2018\n" +
+		"2018-07-05T14:45:00+02:00,05/07/2018\n" +
+		"1530794700.5,05/07/2018\n" +
+		"yesterday,05/07/2018\n" +
+		"2018-07-05 12:45:00,2018-07-05 12:45:00\n"
+	dec := cel.NewDecoder(strings.NewReader(in), layouts)
+
+	want := time.Date(2018, 7, 5, 12, 45, 0, 0, time.UTC)
+	day := time.Date(2018, 7, 5, 0, 0, 0, 0, time.UTC)
+	is.NoErr(dec.Decode(&v))
+	is.Equal(v.Time, want)
+	is.Equal(v.Tagged, day)
+	is.NoErr(dec.Decode(&v))
+	is.True(v.Time.Equal(want))
+	is.NoErr(dec.Decode(&v))
+	is.True(v.Time.Equal(want.Add(500 * time.Millisecond)))
+	is.Equal(fmt.Sprint(dec.Decode(&v)), `line 4: failed to map field Time: unable to convert field value "yesterday" to time.Time: value matches none of the layouts ["2006-01-02 15:04:05" "2006-01-02T15:04:05Z07:00"]`)
+	err := dec.Decode(&v)
+	is.True(strings.HasPrefix(fmt.Sprint(err), `line 5: failed to map field Tagged: unable to convert field value "2018-07-05 12:45:00" to time.Time: parsing time`))
+}
+
+func TestDecoderWithZoneAbbreviations(t *testing.T) {
+	is := is.NewRelaxed(t)
+	var v struct {
+		Time   time.Time `cel:"0"`
+		Tagged time.Time `cel:"1,layout=02/01/2006 15:04 MST"`
+	}
+	zones := cel.WithZoneAbbreviations(map[string]int{"CET": 1 * 60 * 60, "CEST": 2 * 60 * 60})
+	layouts := cel.WithTimeLayouts([]string{"2006-01-02 15:04:05 MST"})
+	in := "2018-07-05 12:45:00 CEST,05/07/2018 12:45 CEST\n" +
+		"2018-01-05 12:45:00 CET,05/01/2018 12:45 UTC\n" +
+		"1530794700,05/07/2018 12:45 XYZ\n"
+	dec := cel.NewDecoder(strings.NewReader(in), zones, layouts)
+
+	is.NoErr(dec.Decode(&v))
+	is.True(v.Time.Equal(time.Date(2018, 7, 5, 10, 45, 0, 0, time.UTC)))
+	is.True(v.Tagged.Equal(v.Time))
+	name, offset := v.Time.Zone()
+	is.Equal(name, "CEST")
+	is.Equal(offset, 2*60*60)
+	is.NoErr(dec.Decode(&v))
+	is.True(v.Time.Equal(time.Date(2018, 1, 5, 11, 45, 0, 0, time.UTC)))
+	is.True(v.Tagged.Equal(time.Date(2018, 1, 5, 12, 45, 0, 0, time.UTC))) // not in the table
+	is.NoErr(dec.Decode(&v))
+	is.True(v.Time.Equal(time.Unix(1530794700, 0)))
+	is.True(v.Tagged.Equal(time.Date(2018, 7, 5, 12, 45, 0, 0, time.UTC))) // unknown, as by time.Parse
+}
+
+func TestDecoderWithEpochBase(t *testing.T) {
+	is := is.NewRelaxed(t)
+	var v struct {
+		Time time.Time `cel:"0"`
+	}
+	base := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	in := "584541900.25\n0\n2018-07-05 12:45:00\n"
+	dec := cel.NewDecoder(strings.NewReader(in), cel.WithEpochBase(base), cel.WithTimeLayouts([]string{"2006-01-02 15:04:05"}))
+
+	want := time.Date(2018, 7, 10, 12, 45, 0, 250000000, time.UTC)
+	is.NoErr(dec.Decode(&v))
+	is.True(v.Time.Equal(want))
+	is.NoErr(dec.Decode(&v))
+	is.True(v.Time.Equal(base))
+	is.NoErr(dec.Decode(&v))
+	is.True(v.Time.Equal(time.Date(2018, 7, 5, 12, 45, 0, 0, time.UTC))) // layouts are absolute
+
+	record, err := cel.MarshalEvent(struct {
+		Time time.Time `cel:"0"`
+	}{want}, cel.WithEpochBase(base))
+	is.NoErr(err)
+	is.Equal(record, []string{"584541900.250000"})
+}
+
+func TestDecoderLine(t *testing.T) {
+	is := is.NewRelaxed(t)
+	var v struct {
+		Line int    `cel:",line"`
+		Type string `cel:"0"`
+	}
+	dec := cel.NewDecoder(strings.NewReader("CHAN_START\n\nANSWER\r\nHANGUP"))
+	var lines []int
+	for dec.Decode(&v) == nil {
+		lines = append(lines, v.Line)
+	}
+	is.Equal(lines, []int{1, 3, 4})
+
+	is.NoErr(cel.UnmarshalEvent([]string{"HANGUP"}, &v))
+	is.Equal(v.Line, 0) // no line context
+
+	var bad struct {
+		Line string `cel:",line"`
+	}
+	err := cel.NewDecoder(strings.NewReader("HANGUP\n")).Decode(&bad)
+	is.Equal(fmt.Sprint(err), "line 1: failed to map field Line: line needs an int field, not string")
+}
+
+func TestDecoderWithMaxFieldSize(t *testing.T) {
+	is := is.NewRelaxed(t)
+	var v struct {
+		Type  string            `cel:"0"`
+		Extra map[string]string `cel:"1,json"`
+	}
+	huge := `"{""k"":""` + strings.Repeat("x", 1<<20) + `""}"`
+	in := `HANGUP,"{""k"":""v""}"` + "\n" + "HANGUP," + huge + "\n" + `CHAN_END,"{}"` + "\n"
+	dec := cel.NewDecoder(strings.NewReader(in), cel.WithMaxFieldSize(1024))
+
+	is.NoErr(dec.Decode(&v))
+	is.Equal(v.Extra, map[string]string{"k": "v"})
+	err := dec.Decode(&v)
+	is.Equal(fmt.Sprint(err), fmt.Sprintf("line 2: field 1 has %d bytes, more than the maximum of 1024", 1<<20+8))
+	is.NoErr(dec.Decode(&v)) // moves on to the next line
+	is.Equal(v.Type, "CHAN_END")
+	is.Equal(dec.Decode(&v), io.EOF)
+}
+
+func TestDecoderWithHoldPartialLines(t *testing.T) {
+	is := is.NewRelaxed(t)
+	var file bytes.Buffer // A file that is still being written.
+	dec := cel.NewDecoder(&file, cel.WithHoldPartialLines(true))
+	var v decoderEvent
+
+	file.WriteString("CHAN_START,a\nANSWER,")
+	is.NoErr(dec.Decode(&v))
+	is.Equal(v, decoderEvent{"CHAN_START", "a"})
+	is.Equal(dec.Decode(&v), io.EOF) // the second line is held
+	is.Equal(dec.Decode(&v), io.EOF)
+	file.WriteString("b")
+	is.Equal(dec.Decode(&v), io.EOF)
+	file.WriteString("c\r\nHANGUP,d\n")
+	is.NoErr(dec.Decode(&v))
+	is.Equal(v, decoderEvent{"ANSWER", "bc"})
+	is.NoErr(dec.Decode(&v))
+	is.Equal(v, decoderEvent{"HANGUP", "d"})
+	is.Equal(dec.Decode(&v), io.EOF)
+
+	file.WriteString("CHAN_START,a\nANSWER,")
+	dec = cel.NewDecoder(&file)
+	is.NoErr(dec.Decode(&v))
+	is.NoErr(dec.Decode(&v))
+	is.Equal(v, decoderEvent{"ANSWER", ""}) // without the option
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += n
+	return n, err
+}
+
+func TestDecoderWithMaxRecords(t *testing.T) {
+	is := is.NewRelaxed(t)
+	in := strings.Repeat("CHAN_START,a\nANSWER,b\nHANGUP,c\n", 10000)
+	r := &countingReader{r: strings.NewReader(in)}
+	skipAnswer := func(record []string) bool { return record[0] != "ANSWER" }
+	dec := cel.FilterDecoder(cel.NewDecoder(r, cel.WithMaxRecords(5)), skipAnswer)
+
+	var events []decoderEvent
+	is.NoErr(dec.DecodeAll(&events))
+	is.Equal(len(events), 5)
+	is.Equal(events[4], decoderEvent{"CHAN_START", "a"})
+	var v decoderEvent
+	is.Equal(dec.Decode(&v), io.EOF)
+	is.True(r.n <= 4096) // no more than the decoder's buffer
+}
+
+func TestDecoderRawLine(t *testing.T) {
+	is := is.NewRelaxed(t)
+	lines := []string{
+		`"CHAN_START", "a" `,
+		`ANSWER,"b"`,
+		`"HANGUP","c,""d"""`,
+		`"CHAN_END"`,
+	}
+	in := lines[0] + "\r\n\n" + lines[1] + "\n" + lines[2] + "\n" + lines[3]
+	dec := cel.FilterDecoder(cel.NewDecoder(strings.NewReader(in)), func(record []string) bool {
+		return record[0] != "ANSWER"
+	})
+	var out []string
+	var v decoderEvent
+	for {
+		err := dec.Decode(&v)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			is.Equal(string(dec.RawLine()), lines[3]) // also set for failing records
+			continue
+		}
+		out = append(out, string(dec.RawLine()))
+	}
+	is.Equal(out, []string{lines[0], lines[2]})
+	is.Equal(dec.RawLine(), nil)
+}
+
+func TestDecoderWithDecodeHook(t *testing.T) {
+	is := is.NewRelaxed(t)
+	var nums []int
+	var total time.Duration
+	hook := func(n int, d time.Duration) {
+		nums = append(nums, n)
+		is.True(d >= 0)
+		total += d
+	}
+	start := time.Now()
+	dec := cel.NewDecoder(strings.NewReader("CHAN_START,a\n\nHANGUP,b\n"), cel.WithDecodeHook(hook))
+	var v decoderEvent
+	for dec.Decode(&v) == nil {
+	}
+	is.Equal(nums, []int{1, 2})
+	is.True(total <= time.Since(start))
+}
+
+func TestDecoderWithUTF8Policy(t *testing.T) {
+	is := is.NewRelaxed(t)
+	in := "CHAN_START,Ren\xe9\xe9 M\n"
+	cases := []struct {
+		policy cel.UTF8Policy
+		name   string
+		err    string
+	}{
+		{cel.UTF8Keep, "Ren\xe9\xe9 M", ""},
+		{cel.UTF8Replace, "Ren� M", ""},
+		{cel.UTF8Reject, "", "line 1: field 1: invalid UTF-8"},
+	}
+	for _, c := range cases {
+		var v decoderEvent
+		err := cel.NewDecoder(strings.NewReader(in), cel.WithUTF8Policy(c.policy)).Decode(&v)
+		if c.err != "" {
+			is.Equal(fmt.Sprint(err), c.err)
+			continue
+		}
+		is.NoErr(err)
+		is.Equal(v.AppData, c.name)
+	}
+}
+
+func TestDecoderDecodeAll(t *testing.T) {
+	is := is.NewRelaxed(t)
+	in := celLine(cel.ChanStart, 0, "1.1", "1.1") +
+		celLine(cel.Answer, 1, "1.1", "1.1") +
+		celLine(cel.Hangup, 2, "1.1", "1.1")
+
+	var values []cel.Event
+	is.NoErr(cel.NewDecoder(strings.NewReader(in)).DecodeAll(&values))
+	var pointers []*cel.Event
+	is.NoErr(cel.NewDecoder(strings.NewReader(in)).DecodeAll(&pointers))
+
+	is.Equal(len(values), 3)
+	is.Equal(len(pointers), 3)
+	for i := range values {
+		is.Equal(*pointers[i], values[i])
+	}
+	is.Equal(values[2].Type, cel.Hangup)
+
+	bad := in + "HANGUP,x\n"
+	values = nil
+	err := cel.NewDecoder(strings.NewReader(bad)).DecodeAll(&values)
+	is.True(strings.HasPrefix(fmt.Sprint(err), "line 4: failed to map field Time"))
+	is.Equal(len(values), 3)
+
+	err = cel.NewDecoder(strings.NewReader(in)).DecodeAll(&[]string{})
+	is.Equal(fmt.Sprint(err), "cel: DecodeAll(*[]string): not a pointer to a slice of structs")
+}
+
+func TestDecoderWithRemap(t *testing.T) {
+	is := is.NewRelaxed(t)
+	// The source has uniqueid, eventtype, linkedid, eventtime and an empty
+	// column, which all other standard columns are taken from.
+	remap := make([]int, 20)
+	for i := range remap {
+		remap[i] = 4
+	}
+	remap[0], remap[1], remap[14], remap[15] = 1, 3, 0, 2
+	in := "1.2,ANSWER,1.1,1530794700,\n"
+
+	var e cel.Event
+	is.NoErr(cel.NewDecoder(strings.NewReader(in), cel.WithRemap(remap)).Decode(&e))
+	is.Equal(e, cel.Event{Type: cel.Answer, Time: time.Unix(1530794700, 0), UniqueID: "1.2", LinkedID: "1.1"})
+
+	err := cel.NewDecoder(strings.NewReader(in), cel.WithRemap(remap[:16])).Decode(&e)
+	is.Equal(fmt.Sprint(err), "line 1: remap table has 16 columns, but cel.Event uses index 19")
+	remap[19] = 5
+	err = cel.NewDecoder(strings.NewReader(in), cel.WithRemap(remap)).Decode(&e)
+	is.Equal(fmt.Sprint(err), "line 1: remap table maps column 19 to index 5, beyond record of length 5")
+}
+
+func TestDecoderWithMissingColumnPolicy(t *testing.T) {
+	is := is.NewRelaxed(t)
+	type event struct {
+		Type  string    `cel:"0"`
+		Time  time.Time `cel:"1"`
+		Extra string    `cel:"2+3"`
+	}
+	in := "HANGUP\n"
+	cases := []struct {
+		policy   cel.MissingColumnPolicy
+		err      string
+		warnings []string
+	}{
+		{cel.MissingColumnError, "line 1: failed to map field Time: index 1 out of range for record of length 1", nil},
+		{cel.MissingColumnZero, "", nil},
+		{cel.MissingColumnZeroWarn, "", []string{
+			"line 1: field Time: index 1 out of range for record of length 1",
+			"line 1: field Extra: index 2 out of range for record of length 1",
+		}},
+	}
+	for _, c := range cases {
+		v := event{Extra: "stale"}
+		dec := cel.NewDecoder(strings.NewReader(in), cel.WithMissingColumnPolicy(c.policy))
+		err := dec.Decode(&v)
+		is.Equal(dec.Warnings(), c.warnings)
+		if c.err != "" {
+			is.Equal(fmt.Sprint(err), c.err)
+			continue
+		}
+		is.NoErr(err)
+		is.Equal(v, event{Type: "HANGUP"})
+		is.Equal(dec.Warnings(), nil)
+	}
+
+	// The base column of secofday follows the policy as well.
+	type secOfDay struct {
+		Type  string    `cel:"0"`
+		Start time.Time `cel:"1,secofday=2"`
+	}
+	in = "HANGUP,3600\n"
+	err := cel.NewDecoder(strings.NewReader(in)).Decode(&secOfDay{})
+	is.Equal(fmt.Sprint(err), "line 1: failed to map field Start: index 2 out of range for record of length 2")
+	v := secOfDay{Start: time.Unix(1530794700, 0)}
+	dec := cel.NewDecoder(strings.NewReader(in), cel.WithMissingColumnPolicy(cel.MissingColumnZeroWarn))
+	is.NoErr(dec.Decode(&v))
+	is.Equal(v, secOfDay{Type: "HANGUP"})
+	is.Equal(dec.Warnings(), []string{"line 1: field Start: index 2 out of range for record of length 2"})
+}
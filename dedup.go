@@ -0,0 +1,47 @@
+package cel
+
+import (
+	"container/list"
+	"encoding/binary"
+	"hash/fnv"
+)
+
+// Dedup makes dec skip records that are identical to one of the last window
+// distinct records it has kept, as happens when overlapping (rotated) files
+// are read one after the other. It modifies and returns dec.
+//
+// The window is maintained in least-recently-used order: a duplicate counts as
+// a new use of the record it duplicates, so a record that keeps being
+// repeated stays in the window. Only records that are not skipped by earlier
+// wrappers of dec enter the window. Records are compared by their 64-bit
+// FNV-1a hash, so a hash collision could make a distinct record be skipped;
+// in practice this is negligible.
+func Dedup(dec *Decoder, window int) *Decoder {
+	seen := make(map[uint64]*list.Element)
+	lru := list.New()
+	dec.filters = append(dec.filters, func(record []string) bool {
+		h := hashRecord(record)
+		if e, ok := seen[h]; ok {
+			lru.MoveToFront(e)
+			return false
+		}
+		seen[h] = lru.PushFront(h)
+		if lru.Len() > window {
+			delete(seen, lru.Remove(lru.Back()).(uint64))
+		}
+		return true
+	})
+	return dec
+}
+
+// hashRecord returns the FNV-1a hash of record. Each field is prefixed with
+// its length, so that records like {"a,b"} and {"a", "b"} differ.
+func hashRecord(record []string) uint64 {
+	h := fnv.New64a()
+	var n [binary.MaxVarintLen64]byte
+	for _, field := range record {
+		h.Write(n[:binary.PutUvarint(n[:], uint64(len(field)))])
+		h.Write([]byte(field))
+	}
+	return h.Sum64()
+}
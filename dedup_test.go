@@ -0,0 +1,39 @@
+package cel_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/VoIPGRID/cel"
+	"github.com/matryer/is"
+)
+
+func TestDedup(t *testing.T) {
+	is := is.NewRelaxed(t)
+	in := strings.Join([]string{
+		"CHAN_START,a",
+		"CHAN_START,a", // duplicate within window
+		"ANSWER,a",
+		"HANGUP,a",
+		"CHAN_END,a",
+		"ANSWER,a",     // duplicate within window
+		"CHAN_START,a", // beyond the window of 3
+		`"CHAN_START,a"`,
+	}, "\n")
+	dec := cel.Dedup(cel.NewDecoder(strings.NewReader(in)), 3)
+
+	var got []string
+	for {
+		var v struct {
+			Type string `cel:"0"`
+		}
+		err := dec.Decode(&v)
+		if err == io.EOF {
+			break
+		}
+		is.NoErr(err)
+		got = append(got, v.Type)
+	}
+	is.Equal(got, []string{"CHAN_START", "ANSWER", "HANGUP", "CHAN_END", "CHAN_START", "CHAN_START,a"})
+}
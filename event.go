@@ -1,8 +1,11 @@
 package cel
 
 import (
+	"bytes"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"math"
 	"reflect"
 	"strconv"
 	"strings"
@@ -11,6 +14,82 @@ import (
 	"github.com/pkg/errors"
 )
 
+// Event is a CEL event in the layout of the stock Master.csv mapping of
+// Asterisk's cel_custom module.
+type Event struct {
+	Type        EventType `cel:"0"`
+	Time        time.Time `cel:"1"`
+	CIDName     string    `cel:"2"`
+	CIDNum      string    `cel:"3"`
+	CIDANI      string    `cel:"4"`
+	CIDRDNIS    string    `cel:"5"`
+	CIDDNID     string    `cel:"6"`
+	Exten       string    `cel:"7"`
+	Context     string    `cel:"8"`
+	ChanName    string    `cel:"9"`
+	AppName     string    `cel:"10"`
+	AppData     string    `cel:"11"`
+	AMAFlags    AMAFlags  `cel:"12"`
+	AccountCode string    `cel:"13"`
+	UniqueID    string    `cel:"14"`
+	LinkedID    string    `cel:"15"`
+	Peer        string    `cel:"16"`
+	UserField   string    `cel:"17"`
+	UserDefType string    `cel:"18"`
+	Extra       string    `cel:"19"` // Usually JSON, may be empty.
+
+	// PeerAccount is not part of the stock layout. Its column can be set
+	// using WithColumn("peeraccount", index).
+	PeerAccount string `cel:"peeraccount,optional"`
+}
+
+// BillingAccounts returns the account code of the channel and that of its
+// peer. peer is empty if the peeraccount column is not decoded.
+func (e Event) BillingAccounts() (local, peer string) {
+	return e.AccountCode, e.PeerAccount
+}
+
+// Clone returns a copy of e that shares no memory with e that could be
+// modified later, such as the record a Decoder reuses. Event currently
+// consists of values and (immutable) strings only, so this is a plain copy;
+// Clone must be extended to deep-copy any slice, map or pointer fields that
+// are added to Event, so that code retaining events keeps working.
+func (e Event) Clone() Event {
+	return e
+}
+
+// ToMap returns the fields of e keyed by the names of their columns (see
+// StandardColumns), plus "peeraccount", as a stable representation that
+// makes mapping events onto other schemas, such as protobuf or Avro messages,
+// mechanical. The eventtime is a time.Time and the amaflags are the code of
+// the flags as an int; all other values are plain strings. All keys are
+// present, also for empty fields.
+func (e Event) ToMap() map[string]interface{} {
+	return map[string]interface{}{
+		standardColumns[ColEventType]:   string(e.Type),
+		standardColumns[ColEventTime]:   e.Time,
+		standardColumns[ColCIDName]:     e.CIDName,
+		standardColumns[ColCIDNum]:      e.CIDNum,
+		standardColumns[ColCIDANI]:      e.CIDANI,
+		standardColumns[ColCIDRDNIS]:    e.CIDRDNIS,
+		standardColumns[ColCIDDNID]:     e.CIDDNID,
+		standardColumns[ColExten]:       e.Exten,
+		standardColumns[ColContext]:     e.Context,
+		standardColumns[ColChanName]:    e.ChanName,
+		standardColumns[ColAppName]:     e.AppName,
+		standardColumns[ColAppData]:     e.AppData,
+		standardColumns[ColAMAFlags]:    int(e.AMAFlags),
+		standardColumns[ColAccountCode]: e.AccountCode,
+		standardColumns[ColUniqueID]:    e.UniqueID,
+		standardColumns[ColLinkedID]:    e.LinkedID,
+		standardColumns[ColPeer]:        e.Peer,
+		standardColumns[ColUserField]:   e.UserField,
+		standardColumns[ColUserDefType]: e.UserDefType,
+		standardColumns[ColExtra]:       e.Extra,
+		"peeraccount":                   e.PeerAccount,
+	}
+}
+
 // An InvalidUnmarshalError describes an invalid argument passed to
 // UnmarshalEvent. (The argument to UnmarshalEvent must be a non-nil pointer
 // to a struct.)
@@ -38,18 +117,166 @@ func (e *InvalidUnmarshalError) Error() string {
 // The struct's exported fields with a struct tag containing a `cel="N"` value
 // will be filled with field N from record.
 //
+// The struct type may also be built at run time using reflect.StructOf, with
+// the tags given in the Tag of each reflect.StructField. Such types work like
+// any other; note that StructOf itself only allows exported fields, and that
+// tags must use the same `cel:"..."` syntax, as they are read using
+// reflect.StructTag.Get.
+//
 // If the struct tag points to an index beyond the length of the given record
-// slice, UnmarshalEvent will panic.
+// slice, UnmarshalEvent returns an error. Decoders can be configured to handle
+// this differently using WithMissingColumnPolicy.
 //
 // Additionally, using a struct tag `cel="N,json"` will take that record
 // field, and use encoding/json.Unmarshal to convert its contents to that
 // struct field. Adding ",noerror" will allow for json.Unmarshal errors to
 // happen silently.
 //
+// As a special case, a ",json" field of type map[string]string accepts
+// objects with values of any type. Strings are used as they are, null becomes
+// an empty string, and other values (numbers, booleans, arrays and objects)
+// are stored as their compact JSON text, as in "16" or `{"a":1}`.
+//
+// Instead of an index, a tag may name a column, as in `cel:"peeraccount"`.
+// Column names are resolved using the WithColumn option of a Decoder; with
+// UnmarshalEvent, no names can be resolved. If a name cannot be resolved,
+// UnmarshalEvent returns an error, unless ",optional" is added to the tag, in
+// which case the field is left alone.
+//
+// A tag may also join several columns into one value, as in
+// `cel:"1+2,layout=2006-01-02 15:04:05"`, for sources that put the date and
+// the time of day in separate columns. The values are joined using a space,
+// or the separator given using ",sep=<separator>".
+//
+// A column that holds a record of its own, using a different delimiter (as
+// in "a;b;c"), can be decoded into a struct (or pointer to a struct) field
+// using ",subcsv=<delimiter>", as in `cel:"18,subcsv=;"`. The column is split
+// like a CSV record, and the struct's tags refer to the resulting fields of
+// the column, starting at 0. Column names cannot be used in these tags. An
+// empty column results in a zero struct, or a nil pointer.
+//
+// A tag without an index and with the ",line" option, as in `cel:",line"`,
+// sets an int field to the number of the line that a Decoder read the record
+// from, starting at 1, to trace events back to their input. UnmarshalEvent
+// has no line context, and sets such fields to zero.
+//
+// Adding ",trim" to a tag removes leading and trailing white space from the
+// field value before it is converted (with or without ",json").
+//
+// Adding ",strip=<characters>" removes any of the given characters from both
+// ends of the value, for instance currency symbols with ",strip=$€". This
+// happens after ",trim", which also removes any white space left over.
+//
 // Without ",json" the supported field types are:
 //  - string
-//  - time.Time (expects Unix time in seconds, or <seconds>.<milliseconds>)
+//  - int, int8, int16, int32, int64 and their unsigned counterparts
+//  - float32, float64
+//  - bool (expects anything strconv.ParseBool accepts, see also WithBoolFormat)
+//  - time.Duration (expects seconds, or anything time.ParseDuration accepts)
+//  - time.Time (expects Unix time in seconds, or <seconds>.<fraction>)
+//  - AMAFlags (expects a code, see WithAMAFlagsCodes, or a name as in "BILLING")
+//  - pointers to any of the above, which are set to nil for empty values
+//  - slices of any of the above, from values separated by "|" (as in
+//    "1530794700.5|1530794730"); empty elements are skipped, and empty
+//    values result in nil slices
+//
+// Integer fields accept an additional ",floatint" option, which allows values
+// written in float form with an all-zero fraction (as in "30.0"). Values with
+// a non-zero fraction (as in "30.5") still return an error. They also accept
+// ",underscores", which allows underscores between digits as separators (as
+// in "1_530_794_700"); underscores anywhere else return an error.
+//
+// Time fields accept a ",layout=<layout>" option, to parse values using
+// time.Parse instead of as Unix time. Values without a time zone are taken to
+// be in UTC. As tag options are separated by commas, layouts cannot contain
+// commas.
+//
+// Time fields accept a ",partialtime" option, which treats a missing seconds
+// or fraction part (as in ".5" or "5.") as zero rather than returning an
+// error.
+//
+// Time and time.Duration fields accept a ",secofday" option for values that
+// hold a time of day as integer seconds since midnight, as in "3600" for
+// 01:00. Values of 86400 and up roll over into the following days. A
+// time.Duration is set to the seconds since midnight. A time.Time is set to
+// that time of day on the date of the event: the date of the time in the
+// eventtime column (ColEventTime, or the column named "eventtime"), parsed
+// like a time field, in its location. With ",secofday=<column>", the date of
+// the given column is used instead. The zero date in UTC is used if the base
+// column is empty, and for the default base also if it is missing, does not
+// parse, or is the field's own column. MarshalEvent writes the seconds since
+// midnight of the base date, taken from the time field of the base column,
+// so that days past the base date are kept.
+//
+// For numeric and time fields (and pointers to them) without ",json", the
+// value "-" is recognized as the marker for an unknown or not applicable
+// value, as is common in telephony logs: pointers are set to nil, and other
+// fields to their zero value, which ",nonzero" turns into an error.
+//
+// For *time.Time fields, ",zerotime=nil" makes a time at the Unix epoch (such
+// as "0" or "0.000000") result in nil as well, so that it cannot be mistaken
+// for an actual time. The default, ",zerotime=epoch", keeps such times.
+//
+// Adding ",nonzero" to a tag makes UnmarshalEvent return an error if the
+// converted value is the zero value of the field's type (for instance an
+// empty string, 0, or a time.Time at the Unix epoch). The check applies to the
+// value after conversion, so it catches values that parsed fine but carry no
+// information. There is no ",default" option to fill in empty values; fields
+// with a missing column that are zeroed by WithMissingColumnPolicy are not
+// converted, so ",nonzero" does not apply to them.
 func UnmarshalEvent(record []string, v interface{}) error {
+	return unmarshalEvent(record, v, &config{}, nil)
+}
+
+// SafeUnmarshalEvent is like UnmarshalEvent, but returns an error instead of
+// panicking, for instance when a field's json.Unmarshaler panics. The error
+// includes the panic value and the field being mapped.
+func SafeUnmarshalEvent(record []string, v interface{}) (err error) {
+	var field string
+	defer func() {
+		if r := recover(); r != nil {
+			if field == "" {
+				err = fmt.Errorf("cel: panic in UnmarshalEvent: %v", r)
+				return
+			}
+			err = fmt.Errorf("cel: panic while mapping field %v: %v", field, r)
+		}
+	}()
+	return unmarshalEvent(record, v, &config{}, &field)
+}
+
+// config holds the decoding settings that can be changed by Options.
+type config struct {
+	decimalComma  bool
+	columns       map[string]int
+	foldNames     bool // Resolve column names case-insensitively.
+	timeLayouts   []string
+	epochBase     *time.Time // Nil for the Unix epoch.
+	amaFlagsCodes map[int]AMAFlags
+	zones         map[string]int
+	boolFormat    *BoolFormat
+	missing       MissingColumnPolicy
+	warn          func(warning string) // Called for MissingColumnZeroWarn.
+	line          int                  // Line number of the record, for ",line" fields.
+}
+
+// column returns the index of the column called name.
+func (c *config) column(name string) (int, bool) {
+	index, ok := c.columns[name]
+	if ok || !c.foldNames {
+		return index, ok
+	}
+	for n, index := range c.columns {
+		if strings.EqualFold(n, name) {
+			return index, true
+		}
+	}
+	return 0, false
+}
+
+// unmarshalEvent implements UnmarshalEvent using the settings in c. If field is
+// not nil, it is set to the name of each field before it is mapped.
+func unmarshalEvent(record []string, v interface{}, c *config, field *string) error {
 	rv := reflect.ValueOf(v)
 	if rv.Kind() != reflect.Ptr || rv.IsNil() {
 		return &InvalidUnmarshalError{reflect.TypeOf(v)}
@@ -59,7 +286,17 @@ func UnmarshalEvent(record []string, v interface{}) error {
 		return &InvalidUnmarshalError{reflect.TypeOf(v)}
 	}
 	for i := 0; i < rv.NumField(); i++ {
-		err := mapField(record, rv.Field(i), rv.Type().Field(i).Tag.Get("cel"))
+		if field != nil {
+			*field = rv.Type().Field(i).Name
+		}
+		err := mapField(record, rv.Field(i), rv.Type().Field(i).Tag.Get("cel"), c)
+		if _, ok := err.(*missingColumnError); ok && c.missing != MissingColumnError {
+			rv.Field(i).Set(reflect.Zero(rv.Field(i).Type()))
+			if c.missing == MissingColumnZeroWarn && c.warn != nil {
+				c.warn(fmt.Sprintf("field %v: %v", rv.Type().Field(i).Name, err))
+			}
+			continue
+		}
 		if err != nil {
 			return errors.Wrapf(err, "failed to map field %v", rv.Type().Field(i).Name)
 		}
@@ -67,7 +304,7 @@ func UnmarshalEvent(record []string, v interface{}) error {
 	return nil
 }
 
-func mapField(record []string, v reflect.Value, tag string) error {
+func mapField(record []string, v reflect.Value, tag string, c *config) error {
 	if tag == "" {
 		return nil
 	}
@@ -75,35 +312,605 @@ func mapField(record []string, v reflect.Value, tag string) error {
 		return nil
 	}
 	tagParts := strings.Split(tag, ",")
-	field, err := strconv.ParseInt(tagParts[0], 10, 0)
-	if err != nil {
-		return errors.Wrapf(err, "bad tag value %q", tag)
+	if isLineTag(tagParts) {
+		if !isInt(v.Kind()) {
+			return fmt.Errorf("line needs an int field, not %s", v.Type())
+		}
+		v.SetInt(int64(c.line))
+		return nil
+	}
+	_, subRecord := option(tagParts, "subcsv")
+	var convert converter
+	if !subRecord && !contains(tagParts, "json") {
+		convert = converterFor(v.Type())
+		if convert == nil {
+			return fmt.Errorf("type %s not implemented", v.Type())
+		}
+	}
+	s, ok, err := columnValue(record, tagParts, c)
+	if !ok || err != nil {
+		return err
+	}
+	s = fieldValue(s, tagParts)
+	if s == unknownMarker && convert != nil && isUnknownMarkerType(v.Type()) {
+		v.Set(reflect.Zero(v.Type()))
+		if contains(tagParts, "nonzero") {
+			return errors.Errorf("field value %q results in zero %s", s, v.Type())
+		}
+		return nil
+	}
+	if isSecOfDay(tagParts) {
+		return mapSecOfDay(record, v, s, tagParts, c)
 	}
-	if contains(tagParts, "json") {
-		if v.Kind() != reflect.Ptr {
-			v = v.Addr()
+	if subRecord {
+		return mapSubRecord(v, s, tagParts, c)
+	}
+	if convert == nil {
+		if isStringMap(v.Type()) {
+			err = unmarshalStringMap(s, v)
+		} else {
+			if v.Kind() != reflect.Ptr {
+				v = v.Addr()
+			}
+			err = json.Unmarshal([]byte(s), v.Interface())
 		}
-		err = json.Unmarshal([]byte(record[field]), v.Interface())
 		if contains(tagParts, "noerror") {
 			return nil
 		}
 		return err
 	}
-	if v.Kind() == reflect.String {
-		v.SetString(record[int(field)])
-	} else if v.Type().PkgPath() == "time" && v.Type().Name() == "Time" {
-		t, err := asteriskTime(record[field])
+	if err := convert(v, s, tagParts, c); err != nil {
+		return err
+	}
+	if contains(tagParts, "nonzero") && isZero(v) {
+		return errors.Errorf("field value %q results in zero %s", s, v.Type())
+	}
+	return nil
+}
+
+// unknownMarker is the field value for unknown or not applicable values of
+// numeric and time fields.
+const unknownMarker = "-"
+
+// isUnknownMarkerType reports whether fields of type t, or of the type t
+// points to, are numeric or time fields that accept unknownMarker.
+func isUnknownMarkerType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	k := t.Kind()
+	return isTimeType(t) || isInt(k) || isUint(k) || k == reflect.Float32 || k == reflect.Float64
+}
+
+// isLineTag reports whether tagParts is a `cel:",line"` tag.
+func isLineTag(tagParts []string) bool {
+	return tagParts[0] == "" && contains(tagParts, "line")
+}
+
+// isSecOfDay reports whether tagParts has the "secofday" option, with or
+// without a base column.
+func isSecOfDay(tagParts []string) bool {
+	_, ok := option(tagParts, "secofday")
+	return ok || contains(tagParts[1:], "secofday")
+}
+
+// mapSecOfDay sets time.Time or time.Duration v to the seconds since midnight
+// in s, for the "secofday" option.
+func mapSecOfDay(record []string, v reflect.Value, s string, tagParts []string, c *config) error {
+	if !isTimeType(v.Type()) && v.Type() != durationType {
+		return fmt.Errorf("secofday needs a time.Time or time.Duration field, not %s", v.Type())
+	}
+	sec, err := strconv.ParseInt(s, 10, 0)
+	if err != nil {
+		return errors.Wrapf(err, "unable to convert field value %q to seconds of day", s)
+	}
+	if sec < 0 {
+		return errors.Errorf("seconds of day %d is negative", sec)
+	}
+	d := time.Duration(sec) * time.Second
+	if v.Type() == durationType {
+		v.SetInt(int64(d))
+		return nil
+	}
+	base := zeroDate
+	if column, _ := option(tagParts, "secofday"); column != "" {
+		bs, _, err := columnValue(record, []string{column}, c)
+		if _, ok := err.(*missingColumnError); ok {
+			return err // For WithMissingColumnPolicy.
+		}
+		if err != nil {
+			return errors.Wrap(err, "bad secofday base")
+		}
+		if bs != "" {
+			var t time.Time
+			if err := convertTime(reflect.ValueOf(&t).Elem(), bs, []string{column}, c); err != nil {
+				return errors.Wrap(err, "bad secofday base")
+			}
+			base = midnight(t)
+		}
+	} else if index, ok := secOfDayBaseIndex(tagParts, c); ok && index < len(record) && record[index] != "" {
+		var t time.Time
+		if convertTime(reflect.ValueOf(&t).Elem(), record[index], []string{""}, c) == nil {
+			base = midnight(t)
+		}
+	}
+	v.Set(reflect.ValueOf(base.Add(d)))
+	return nil
+}
+
+// zeroDate is the base date of "secofday" times without a base date.
+var zeroDate = time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// secOfDayBaseIndex returns the index of the base column of a "secofday"
+// field: the column of the option, or else the eventtime column (see
+// ColEventTime), unless that is the field's own column. ok is false if there
+// is no such column.
+func secOfDayBaseIndex(tagParts []string, c *config) (index int, ok bool) {
+	if column, _ := option(tagParts, "secofday"); column != "" {
+		return columnIndex(column, c)
+	}
+	index, ok = c.column(standardColumns[ColEventTime])
+	if !ok {
+		index = ColEventTime
+	}
+	if own, ok := columnIndex(tagParts[0], c); ok && own == index {
+		return 0, false
+	}
+	return index, true
+}
+
+// columnIndex returns the index that a single column of a tag refers to, a
+// number or a column name.
+func columnIndex(column string, c *config) (int, bool) {
+	if index, err := strconv.Atoi(column); err == nil {
+		return index, true
+	}
+	return c.column(column)
+}
+
+// midnight returns the start of the day of t, in its location.
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// mapSubRecord splits s using the delimiter of the "subcsv" option, and
+// unmarshals the resulting record into struct (or pointer to struct) v.
+func mapSubRecord(v reflect.Value, s string, tagParts []string, c *config) error {
+	r, err := subCSVDelimiter(tagParts)
+	if err != nil {
+		return err
+	}
+	if v.Kind() == reflect.Ptr {
+		if s == "" {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("subcsv needs a struct, not %s", v.Type())
+	}
+	if s == "" {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	cr := csv.NewReader(strings.NewReader(s))
+	cr.Comma = r
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	record, err := cr.Read()
+	if err != nil {
+		return err
+	}
+	sub := *c
+	sub.columns = nil
+	return unmarshalEvent(record, v.Addr().Interface(), &sub, nil)
+}
+
+func subCSVDelimiter(tagParts []string) (rune, error) {
+	sep, _ := option(tagParts, "subcsv")
+	r := []rune(sep)
+	if len(r) != 1 {
+		return 0, errors.Errorf("bad subcsv option %q: expected a single character", sep)
+	}
+	return r[0], nil
+}
+
+// maxIndex returns the highest record index that the tags of struct type t
+// use, or -1 if there is none.
+func maxIndex(t reflect.Type, c *config) int {
+	max := -1
+	for i := 0; i < t.NumField(); i++ {
+		tagParts := strings.Split(t.Field(i).Tag.Get("cel"), ",")
+		for _, part := range strings.Split(tagParts[0], "+") {
+			index, err := strconv.Atoi(part)
+			if err != nil {
+				var ok bool
+				if index, ok = c.column(part); !ok {
+					continue
+				}
+			}
+			if index > max {
+				max = index
+			}
+		}
+	}
+	return max
+}
+
+// columnValue returns the value of the column(s) that tagParts refers to. ok
+// is false if the field should be left alone.
+func columnValue(record []string, tagParts []string, c *config) (s string, ok bool, err error) {
+	if strings.Contains(tagParts[0], "+") {
+		s, err := joinedValue(record, tagParts)
+		return s, err == nil, err
+	}
+	field, err := strconv.ParseInt(tagParts[0], 10, 0)
+	if err != nil {
+		index, ok := c.column(tagParts[0])
+		if !ok && contains(tagParts, "optional") {
+			return "", false, nil
+		}
+		if !ok {
+			return "", false, errors.Wrapf(err, "bad tag value %q", strings.Join(tagParts, ","))
+		}
+		field = int64(index)
+	}
+	if field < 0 || int(field) >= len(record) {
+		return "", false, &missingColumnError{int(field), len(record)}
+	}
+	return record[field], true, nil
+}
+
+// A missingColumnError is returned for tags that point to an index beyond the
+// length of the record.
+type missingColumnError struct {
+	index, length int
+}
+
+func (e *missingColumnError) Error() string {
+	return fmt.Sprintf("index %d out of range for record of length %d", e.index, e.length)
+}
+
+// joinedValue returns the values of the columns in a tag like "1+2", joined
+// by the separator given by the "sep" option, or a space.
+func joinedValue(record []string, tagParts []string) (string, error) {
+	sep, ok := option(tagParts, "sep")
+	if !ok {
+		sep = " "
+	}
+	var values []string
+	for _, part := range strings.Split(tagParts[0], "+") {
+		field, err := strconv.ParseInt(part, 10, 0)
 		if err != nil {
-			return errors.Wrapf(err, "unable to convert field value %q to time.Time", record[field])
+			return "", errors.Wrapf(err, "bad tag value %q", strings.Join(tagParts, ","))
 		}
-		v.Set(reflect.ValueOf(t))
+		if field < 0 || int(field) >= len(record) {
+			return "", &missingColumnError{int(field), len(record)}
+		}
+		values = append(values, record[field])
+	}
+	return strings.Join(values, sep), nil
+}
+
+func isStringMap(t reflect.Type) bool {
+	return t.Kind() == reflect.Map && t.Key().Kind() == reflect.String && t.Elem().Kind() == reflect.String
+}
+
+// unmarshalStringMap decodes JSON object s into v, a map of strings to
+// strings, converting values of any type to strings.
+func unmarshalStringMap(s string, v reflect.Value) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(s), &raw); err != nil {
+		return err
+	}
+	m := reflect.MakeMapWithSize(v.Type(), len(raw))
+	for k, r := range raw {
+		var value string
+		switch {
+		case string(r) == "null":
+		case r[0] == '"':
+			if err := json.Unmarshal(r, &value); err != nil {
+				return err
+			}
+		default:
+			var b bytes.Buffer
+			if err := json.Compact(&b, r); err != nil {
+				return err
+			}
+			value = b.String()
+		}
+		m.SetMapIndex(reflect.ValueOf(k).Convert(v.Type().Key()), reflect.ValueOf(value).Convert(v.Type().Elem()))
+	}
+	v.Set(m)
+	return nil
+}
+
+// fieldValue applies the tag options that modify a field value before it is
+// converted.
+func fieldValue(s string, tagParts []string) string {
+	trim := contains(tagParts, "trim")
+	if trim {
+		s = strings.TrimSpace(s)
+	}
+	if chars, ok := option(tagParts, "strip"); ok {
+		s = strings.Trim(s, chars)
+		if trim {
+			s = strings.TrimSpace(s)
+		}
+	}
+	return s
+}
+
+// isZero reports whether v is the zero value for its type. For time.Time, the
+// Unix epoch is also considered zero, as that is what "0" is converted to.
+func isZero(v reflect.Value) bool {
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.IsZero() || t.Equal(time.Unix(0, 0))
+	}
+	return v.IsZero()
+}
+
+// A converter sets v from the string value s of a record field.
+type converter func(v reflect.Value, s string, tagParts []string, c *config) error
+
+// converterFor returns the converter for values of type t, or nil if the type
+// is not supported.
+func converterFor(t reflect.Type) converter {
+	switch {
+	case t.Kind() == reflect.String:
+		return convertString
+	case isTimeType(t):
+		return convertTime
+	case t == durationType:
+		return convertDuration
+	case t == amaFlagsType:
+		return convertAMAFlags
+	case isInt(t.Kind()):
+		return convertInt
+	case isUint(t.Kind()):
+		return convertUint
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return convertFloat
+	case t.Kind() == reflect.Bool:
+		return convertBool
+	case t.Kind() == reflect.Ptr:
+		if elem := converterFor(t.Elem()); elem != nil {
+			return ptrConverter(elem)
+		}
+	case t.Kind() == reflect.Slice:
+		if elem := converterFor(t.Elem()); elem != nil {
+			return sliceConverter(elem)
+		}
+	}
+	return nil
+}
+
+// sliceConverter returns a converter for slices of values that elem
+// converts, from values separated by sliceSeparator. Empty elements are
+// skipped; an empty string results in a nil slice.
+func sliceConverter(elem converter) converter {
+	return func(v reflect.Value, s string, tagParts []string, c *config) error {
+		slice := reflect.Zero(v.Type())
+		for i, part := range strings.Split(s, sliceSeparator) {
+			if part == "" {
+				continue
+			}
+			e := reflect.New(v.Type().Elem()).Elem()
+			if err := elem(e, part, tagParts, c); err != nil {
+				return errors.Wrapf(err, "element %d", i)
+			}
+			slice = reflect.Append(slice, e)
+		}
+		v.Set(slice)
+		return nil
+	}
+}
+
+// sliceSeparator separates the elements of slice fields.
+const sliceSeparator = "|"
+
+// ptrConverter returns a converter for pointers to values that elem converts.
+// An empty string results in a nil pointer.
+func ptrConverter(elem converter) converter {
+	return func(v reflect.Value, s string, tagParts []string, c *config) error {
+		if s == "" {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		p := reflect.New(v.Type().Elem())
+		if err := elem(p.Elem(), s, tagParts, c); err != nil {
+			return err
+		}
+		if isTimeType(v.Type().Elem()) {
+			switch zt, _ := option(tagParts, "zerotime"); zt {
+			case "", "epoch":
+			case "nil":
+				if isZero(p.Elem()) {
+					v.Set(reflect.Zero(v.Type()))
+					return nil
+				}
+			default:
+				return errors.Errorf("bad zerotime option %q", zt)
+			}
+		}
+		v.Set(p)
+		return nil
+	}
+}
+
+func convertString(v reflect.Value, s string, tagParts []string, c *config) error {
+	v.SetString(s)
+	return nil
+}
+
+func convertTime(v reflect.Value, s string, tagParts []string, c *config) error {
+	var t time.Time
+	var err error
+	if layout, ok := option(tagParts, "layout"); ok {
+		t, err = parseLayout(layout, s, c)
+	} else if len(c.timeLayouts) > 0 {
+		t, err = parseTimeLayouts(s, contains(tagParts, "partialtime"), c)
 	} else {
-		return fmt.Errorf("type %s not implemented", v.Type())
+		t, err = epochTime(s, contains(tagParts, "partialtime"), c)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "unable to convert field value %q to time.Time", s)
+	}
+	v.Set(reflect.ValueOf(t))
+	return nil
+}
+
+func convertInt(v reflect.Value, s string, tagParts []string, c *config) error {
+	s, err := intString(s, tagParts)
+	if err != nil {
+		return err
+	}
+	n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+	if err != nil {
+		return errors.Wrapf(err, "unable to convert field value %q to %s", s, v.Type())
+	}
+	v.SetInt(n)
+	return nil
+}
+
+func convertUint(v reflect.Value, s string, tagParts []string, c *config) error {
+	s, err := intString(s, tagParts)
+	if err != nil {
+		return err
+	}
+	n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+	if err != nil {
+		return errors.Wrapf(err, "unable to convert field value %q to %s", s, v.Type())
+	}
+	v.SetUint(n)
+	return nil
+}
+
+func convertFloat(v reflect.Value, s string, tagParts []string, c *config) error {
+	f, err := strconv.ParseFloat(decimalString(s, c), v.Type().Bits())
+	if err != nil {
+		return errors.Wrapf(err, "unable to convert field value %q to %s", s, v.Type())
+	}
+	v.SetFloat(f)
+	return nil
+}
+
+func convertBool(v reflect.Value, s string, tagParts []string, c *config) error {
+	b, err := c.boolFormat.parse(s)
+	if err != nil {
+		return errors.Wrapf(err, "unable to convert field value %q to %s", s, v.Type())
+	}
+	v.SetBool(b)
+	return nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// convertDuration accepts a number of seconds, or anything accepted by
+// time.ParseDuration.
+func convertDuration(v reflect.Value, s string, tagParts []string, c *config) error {
+	s = decimalString(s, c)
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		ns := f * float64(time.Second)
+		if math.IsNaN(ns) || ns >= math.MaxInt64 || ns < math.MinInt64 {
+			return errors.Errorf("unable to convert field value %q to time.Duration: out of range", s)
+		}
+		v.SetInt(int64(ns))
+		return nil
 	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return errors.Wrapf(err, "unable to convert field value %q to time.Duration", s)
+	}
+	v.SetInt(int64(d))
 	return nil
 }
 
-func asteriskTime(s string) (time.Time, error) {
+// decimalString prepares s for float parsing, by replacing a decimal comma
+// with a period if the decimalComma setting is enabled.
+func decimalString(s string, c *config) string {
+	if c.decimalComma {
+		return strings.Replace(s, ",", ".", 1)
+	}
+	return s
+}
+
+// intString prepares s for integer parsing. With the "underscores" option
+// digit separators are removed, so "1_000" becomes "1000". With the
+// "floatint" option an all-zero fraction is trimmed, so "30.0" becomes "30".
+func intString(s string, tagParts []string) (string, error) {
+	if contains(tagParts, "underscores") {
+		for i := 0; i < len(s); i++ {
+			if s[i] == '_' && (i == 0 || i == len(s)-1 || !isDigit(s[i-1]) || !isDigit(s[i+1])) {
+				return "", errors.Errorf("field value %q has a misplaced underscore", s)
+			}
+		}
+		s = strings.Replace(s, "_", "", -1)
+	}
+	if !contains(tagParts, "floatint") {
+		return s, nil
+	}
+	i := strings.IndexByte(s, '.')
+	if i < 0 {
+		return s, nil
+	}
+	if strings.Trim(s[i+1:], "0") != "" {
+		return "", errors.Errorf("field value %q is not an integer", s)
+	}
+	return s[:i], nil
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+// parseTimeLayouts parses s using the first of layouts that matches, or else
+// as Unix time.
+func parseTimeLayouts(s string, partial bool, c *config) (time.Time, error) {
+	for _, layout := range c.timeLayouts {
+		if t, err := parseLayout(layout, s, c); err == nil {
+			return t, nil
+		}
+	}
+	t, err := epochTime(s, partial, c)
+	if err != nil && strings.Trim(s, "0123456789.") != "" {
+		return time.Time{}, errors.Errorf("value matches none of the layouts %q", c.timeLayouts)
+	}
+	return t, err
+}
+
+// parseLayout is like time.Parse, but resolves zone abbreviations using the
+// table of c (see WithZoneAbbreviations).
+func parseLayout(layout, s string, c *config) (time.Time, error) {
+	t, err := time.Parse(layout, s)
+	if err != nil || c.zones == nil {
+		return t, err
+	}
+	name, _ := t.Zone()
+	offset, ok := c.zones[name]
+	if !ok {
+		return t, nil
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.FixedZone(name, offset)), nil
+}
+
+// epochTime is like asteriskTime, but counts from the epoch base of c.
+func epochTime(s string, partial bool, c *config) (time.Time, error) {
+	t, err := asteriskTime(s, partial)
+	if err != nil || c.epochBase == nil {
+		return t, err
+	}
+	return time.Unix(c.epochBase.Unix()+t.Unix(), int64(c.epochBase.Nanosecond()+t.Nanosecond())), nil
+}
+
+// asteriskTime parses s as "<seconds>" or "<seconds>.<fraction>". If
+// partial is set, a missing seconds or fraction part (as in ".5" or "5.") is
+// treated as zero, instead of being an error.
+func asteriskTime(s string, partial bool) (time.Time, error) {
 	if s == "" {
 		return time.Time{}, errors.New("input is empty string")
 	}
@@ -111,21 +918,70 @@ func asteriskTime(s string) (time.Time, error) {
 	if len(ss) > 2 {
 		return time.Time{}, errors.New("expected at most one period in string")
 	}
-	sec, err := strconv.ParseInt(ss[0], 10, 0)
-	if err != nil {
-		return time.Time{}, err
+	if len(ss) == 2 && ss[0] == "" && ss[1] == "" {
+		return time.Time{}, errors.New("missing seconds and fraction part")
 	}
-	var nsec int64
-	if len(ss) == 2 {
-		nsec, err = strconv.ParseInt(ss[1], 10, 0)
+	var sec int64
+	if ss[0] != "" {
+		var err error
+		sec, err = strconv.ParseInt(ss[0], 10, 0)
 		if err != nil {
 			return time.Time{}, err
 		}
-		nsec *= 1000
+	} else if !partial {
+		return time.Time{}, errors.New("missing seconds part")
+	}
+	var nsec int64
+	if len(ss) == 2 {
+		if ss[1] != "" {
+			var err error
+			nsec, err = parseFraction(ss[1])
+			if err != nil {
+				return time.Time{}, err
+			}
+		} else if !partial {
+			return time.Time{}, errors.New("missing fraction part")
+		}
 	}
 	return time.Unix(sec, nsec), nil
 }
 
+// parseFraction returns the number of nanoseconds that the decimal fraction
+// s (the digits after the period) represents.
+func parseFraction(s string) (int64, error) {
+	if len(s) > 9 {
+		return 0, errors.New("fraction has more than 9 digits")
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return 0, errors.Errorf("fraction %q is not a number", s)
+		}
+	}
+	return strconv.ParseInt(s+strings.Repeat("0", 9-len(s)), 10, 64)
+}
+
+func isTimeType(t reflect.Type) bool {
+	return t.PkgPath() == "time" && t.Name() == "Time"
+}
+
+func isInt(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Int64
+}
+
+func isUint(k reflect.Kind) bool {
+	return k >= reflect.Uint && k <= reflect.Uint64
+}
+
+// option returns the value of the "key=value" option in tagParts.
+func option(tagParts []string, key string) (string, bool) {
+	for _, part := range tagParts[1:] {
+		if strings.HasPrefix(part, key+"=") {
+			return part[len(key)+1:], true
+		}
+	}
+	return "", false
+}
+
 func contains(haystack []string, needle string) bool {
 	for _, s := range haystack {
 		if s == needle {
@@ -2,6 +2,8 @@ package cel_test
 
 import (
 	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -61,3 +63,502 @@ func TestUnmarshalEvent(t *testing.T) {
 	is.Equal(v.Number, 1234)
 	is.Equal(v.JSON.Field, 42)
 }
+
+func TestUnmarshalEventInt(t *testing.T) {
+	is := is.NewRelaxed(t)
+	v := struct {
+		Int      int    `cel:"0"`
+		Int8     int8   `cel:"0"`
+		Uint16   uint16 `cel:"0"`
+		FloatInt int64  `cel:"1,floatint"`
+	}{}
+	err := cel.UnmarshalEvent([]string{"30", "30.0"}, &v)
+	is.NoErr(err)
+	is.Equal(v.Int, 30)
+	is.Equal(v.Int8, int8(30))
+	is.Equal(v.Uint16, uint16(30))
+	is.Equal(v.FloatInt, int64(30))
+
+	cases := []struct {
+		in  []string
+		err string
+	}{
+		{[]string{"30", "30.5"}, `failed to map field FloatInt: field value "30.5" is not an integer`},
+		{[]string{"30.0", "30"}, `failed to map field Int: unable to convert field value "30.0" to int: strconv.ParseInt: parsing "30.0": invalid syntax`},
+		{[]string{"300", "30"}, `failed to map field Int8: unable to convert field value "300" to int8: strconv.ParseInt: parsing "300": value out of range`},
+	}
+	for _, c := range cases {
+		err := cel.UnmarshalEvent(c.in, &v)
+		is.Equal(fmt.Sprint(err), c.err)
+	}
+}
+
+func TestUnmarshalEventUnderscores(t *testing.T) {
+	is := is.NewRelaxed(t)
+	var v struct {
+		ID int64 `cel:"0,underscores"`
+	}
+	is.NoErr(cel.UnmarshalEvent([]string{"1_530_794_700"}, &v))
+	is.Equal(v.ID, int64(1530794700))
+	is.NoErr(cel.UnmarshalEvent([]string{"-1_0"}, &v))
+	is.Equal(v.ID, int64(-10))
+	var u struct {
+		Uint uint32 `cel:"0,underscores,floatint"`
+	}
+	is.NoErr(cel.UnmarshalEvent([]string{"1_530_794_700.0"}, &u))
+	is.Equal(u.Uint, uint32(1530794700))
+
+	for _, in := range []string{"_1", "1_", "1__0", "1_.0", "-_1"} {
+		err := cel.UnmarshalEvent([]string{in}, &v)
+		is.Equal(fmt.Sprint(err), fmt.Sprintf("failed to map field ID: field value %q has a misplaced underscore", in))
+	}
+
+	var without struct {
+		ID int64 `cel:"0"`
+	}
+	err := cel.UnmarshalEvent([]string{"1_530_794_700"}, &without)
+	is.Equal(fmt.Sprint(err), `failed to map field ID: unable to convert field value "1_530_794_700" to int64: strconv.ParseInt: parsing "1_530_794_700": invalid syntax`)
+}
+
+func TestUnmarshalEventNonZero(t *testing.T) {
+	is := is.NewRelaxed(t)
+	type event struct {
+		Time   time.Time `cel:"0,nonzero"`
+		Number int       `cel:"1,nonzero"`
+		Type   string    `cel:"2,nonzero"`
+	}
+	var v event
+	is.NoErr(cel.UnmarshalEvent([]string{"1530794700.5", "42", "CHAN_START"}, &v))
+
+	cases := []struct {
+		in  []string
+		err string
+	}{
+		{[]string{"0", "42", "CHAN_START"}, `failed to map field Time: field value "0" results in zero time.Time`},
+		{[]string{"0.000000", "42", "CHAN_START"}, `failed to map field Time: field value "0.000000" results in zero time.Time`},
+		{[]string{"1530794700", "0", "CHAN_START"}, `failed to map field Number: field value "0" results in zero int`},
+		{[]string{"1530794700", "42", ""}, `failed to map field Type: field value "" results in zero string`},
+	}
+	for _, c := range cases {
+		err := cel.UnmarshalEvent(c.in, &event{})
+		is.Equal(fmt.Sprint(err), c.err)
+	}
+}
+
+func TestUnmarshalEventUnknownMarker(t *testing.T) {
+	is := is.NewRelaxed(t)
+	var v struct {
+		Pointer  *int          `cel:"0"`
+		Value    int           `cel:"1"`
+		Time     time.Time     `cel:"2"`
+		TimePtr  *time.Time    `cel:"3"`
+		Duration time.Duration `cel:"4,trim"`
+		Float    float64       `cel:"5"`
+		Type     string        `cel:"6"`
+	}
+	n := 42
+	v.Pointer = &n
+	v.Value = 42
+	is.NoErr(cel.UnmarshalEvent([]string{"-", "-", "-", "-", " - ", "-", "-"}, &v))
+	is.Equal(v.Pointer, nil)
+	is.Equal(v.Value, 0)
+	is.True(v.Time.IsZero())
+	is.Equal(v.TimePtr, nil)
+	is.Equal(v.Duration, time.Duration(0))
+	is.Equal(v.Float, 0.0)
+	is.Equal(v.Type, "-") // strings keep the marker
+
+	var nonZero struct {
+		Value int `cel:"0,nonzero"`
+	}
+	err := cel.UnmarshalEvent([]string{"-"}, &nonZero)
+	is.Equal(fmt.Sprint(err), `failed to map field Value: field value "-" results in zero int`)
+
+	var booleans struct {
+		Bool bool `cel:"0"`
+	}
+	is.True(cel.UnmarshalEvent([]string{"-"}, &booleans) != nil)
+
+	var withJSON struct {
+		Number int `cel:"0,json"`
+	}
+	err = cel.UnmarshalEvent([]string{"-"}, &withJSON)
+	is.True(strings.HasPrefix(fmt.Sprint(err), "failed to map field Number: "))
+}
+
+func TestUnmarshalEventPartialTime(t *testing.T) {
+	is := is.NewRelaxed(t)
+	cases := []struct {
+		in      string
+		strict  string
+		partial string
+	}{
+		{".5", `failed to map field Time: unable to convert field value ".5" to time.Time: missing seconds part`, "1970-01-01 00:00:00.5 +0000 UTC"},
+		{"5.", `failed to map field Time: unable to convert field value "5." to time.Time: missing fraction part`, "1970-01-01 00:00:05 +0000 UTC"},
+		{".", `failed to map field Time: unable to convert field value "." to time.Time: missing seconds and fraction part`, `failed to map field Time: unable to convert field value "." to time.Time: missing seconds and fraction part`},
+		{"5.-3", `failed to map field Time: unable to convert field value "5.-3" to time.Time: fraction "-3" is not a number`, `failed to map field Time: unable to convert field value "5.-3" to time.Time: fraction "-3" is not a number`},
+		{"5.25", "1970-01-01 00:00:05.25 +0000 UTC", "1970-01-01 00:00:05.25 +0000 UTC"},
+	}
+	result := func(v time.Time, err error) string {
+		if err != nil {
+			return err.Error()
+		}
+		return v.UTC().String()
+	}
+	for _, c := range cases {
+		var strict struct {
+			Time time.Time `cel:"0"`
+		}
+		var partial struct {
+			Time time.Time `cel:"0,partialtime"`
+		}
+		is.Equal(result(strict.Time, cel.UnmarshalEvent([]string{c.in}, &strict)), c.strict)
+		is.Equal(result(partial.Time, cel.UnmarshalEvent([]string{c.in}, &partial)), c.partial)
+	}
+}
+
+func TestUnmarshalEventTimePointer(t *testing.T) {
+	is := is.NewRelaxed(t)
+	epoch := time.Unix(0, 0)
+	answer := time.Unix(1530794700, 0)
+	cases := []struct {
+		in        string
+		keep, nil *time.Time
+	}{
+		{"", nil, nil},
+		{"0", &epoch, nil},
+		{"0.000000", &epoch, nil},
+		{"1530794700", &answer, &answer},
+	}
+	for _, c := range cases {
+		var v struct {
+			Keep    *time.Time `cel:"0"`
+			Epoch   *time.Time `cel:"0,zerotime=epoch"`
+			Nil     *time.Time `cel:"0,zerotime=nil"`
+			Counter *int       `cel:"1"`
+		}
+		is.NoErr(cel.UnmarshalEvent([]string{c.in, ""}, &v))
+		is.Equal(v.Keep, c.keep)
+		is.Equal(v.Epoch, c.keep)
+		is.Equal(v.Nil, c.nil)
+		is.Equal(v.Counter, nil)
+	}
+
+	var v struct {
+		Time *time.Time `cel:"0,zerotime=never"`
+	}
+	err := cel.UnmarshalEvent([]string{"0"}, &v)
+	is.Equal(fmt.Sprint(err), `failed to map field Time: bad zerotime option "never"`)
+}
+
+func TestUnmarshalEventStandard(t *testing.T) {
+	is := is.NewRelaxed(t)
+	record := []string{
+		"HANGUP", "1530794700.987654", "Alice", "1001", "1001", "", "", "2001", "from-internal",
+		"SIP/1001-00000001", "", "", "3", "acc1", "1530794690.1", "1530794690.1",
+		"", "", "", `{"hangupcause":16,"hangupsource":"SIP/2001-00000002","dialstatus":"ANSWER"}`,
+	}
+	var e cel.Event
+	is.NoErr(cel.UnmarshalEvent(record, &e))
+	is.Equal(e.Type, cel.Hangup)
+	is.Equal(e.Time.UTC(), time.Date(2018, 7, 5, 12, 45, 0, 987654000, time.UTC))
+	is.Equal(e.CIDName, "Alice")
+	is.Equal(e.Context, "from-internal")
+	is.Equal(e.ChanName, "SIP/1001-00000001")
+	is.Equal(e.AMAFlags, cel.AMAFlagsDocumentation)
+	is.Equal(e.UniqueID, "1530794690.1")
+	is.Equal(e.LinkedID, "1530794690.1")
+	is.Equal(e.Extra, record[19])
+}
+
+func TestEventToMap(t *testing.T) {
+	is := is.NewRelaxed(t)
+	var e cel.Event
+	line := `"HANGUP","1530794700.5","Alice","1001","1001","","","2001","from-internal","SIP/1001-01","Dial","SIP/2001","3","acc1","1.1","1.1","SIP/2001-02","queue=sales","","{""dialstatus"":""ANSWER""}"`
+	is.NoErr(cel.UnmarshalLine(line, &e))
+	e.PeerAccount = "acc2"
+	is.Equal(e.ToMap(), map[string]interface{}{
+		"eventtype":   "HANGUP",
+		"eventtime":   time.Unix(1530794700, 500000000),
+		"cid_name":    "Alice",
+		"cid_num":     "1001",
+		"cid_ani":     "1001",
+		"cid_rdnis":   "",
+		"cid_dnid":    "",
+		"exten":       "2001",
+		"context":     "from-internal",
+		"channame":    "SIP/1001-01",
+		"appname":     "Dial",
+		"appdata":     "SIP/2001",
+		"amaflags":    3,
+		"accountcode": "acc1",
+		"uniqueid":    "1.1",
+		"linkedid":    "1.1",
+		"peer":        "SIP/2001-02",
+		"userfield":   "queue=sales",
+		"userdeftype": "",
+		"extra":       `{"dialstatus":"ANSWER"}`,
+		"peeraccount": "acc2",
+	})
+	is.Equal(len(cel.Event{}.ToMap()), len(cel.StandardColumns)+1)
+}
+
+func TestEventClone(t *testing.T) {
+	is := is.NewRelaxed(t)
+	record := []string{"CHAN_START", "1530794700", "Alice", "1001", "", "", "", "2001", "from-internal", "SIP/1001-00000001", "", "", "3", "", "1.1", "1.1", "", "", "", ""}
+	var e cel.Event
+	is.NoErr(cel.UnmarshalEvent(record, &e))
+	c := e.Clone()
+	is.Equal(c, e)
+
+	record[2] = "Mallory"
+	is.NoErr(cel.UnmarshalEvent(record, &e))
+	is.Equal(e.CIDName, "Mallory")
+	is.Equal(c.CIDName, "Alice")
+}
+
+func TestUnmarshalEventFloatDuration(t *testing.T) {
+	is := is.NewRelaxed(t)
+	var v struct {
+		Cost     float32       `cel:"0"`
+		Seconds  time.Duration `cel:"1"`
+		Duration time.Duration `cel:"2"`
+	}
+	is.NoErr(cel.UnmarshalEvent([]string{"0.25", "30", "1m30s"}, &v))
+	is.Equal(v.Cost, float32(0.25))
+	is.Equal(v.Seconds, 30*time.Second)
+	is.Equal(v.Duration, 90*time.Second)
+
+	err := cel.UnmarshalEvent([]string{"0.25", "soon", ""}, &v)
+	is.Equal(fmt.Sprint(err), `failed to map field Seconds: unable to convert field value "soon" to time.Duration: time: invalid duration "soon"`)
+
+	for _, s := range []string{"NaN", "Inf", "-Inf", "1e300", "-1e300", "9223372037"} {
+		err := cel.UnmarshalEvent([]string{"0.25", s, ""}, &v)
+		is.Equal(fmt.Sprint(err), fmt.Sprintf(`failed to map field Seconds: unable to convert field value %q to time.Duration: out of range`, s))
+	}
+	is.NoErr(cel.UnmarshalEvent([]string{"0.25", "-9223372036", "1s"}, &v))
+	is.Equal(v.Seconds, -9223372036*time.Second)
+}
+
+type panicky struct{}
+
+func (panicky) UnmarshalJSON([]byte) error {
+	panic("deliberate")
+}
+
+func TestSafeUnmarshalEvent(t *testing.T) {
+	is := is.NewRelaxed(t)
+	var v struct {
+		Type    string  `cel:"0"`
+		Panicky panicky `cel:"1,json"`
+	}
+	err := cel.SafeUnmarshalEvent([]string{"CHAN_START", "{}"}, &v)
+	is.Equal(fmt.Sprint(err), "cel: panic while mapping field Panicky: deliberate")
+	is.Equal(v.Type, "CHAN_START")
+
+	is.Equal(fmt.Sprint(cel.SafeUnmarshalEvent([]string{"CHAN_START"}, &v)), "failed to map field Panicky: index 1 out of range for record of length 1")
+	is.Equal(fmt.Sprint(cel.SafeUnmarshalEvent(nil, 42)), "cel: UnmarshalEvent(non-pointer int)")
+}
+
+func TestUnmarshalEventMissingColumn(t *testing.T) {
+	is := is.NewRelaxed(t)
+	var v struct {
+		Type    string `cel:"0"`
+		AppData string `cel:"11"`
+	}
+	err := cel.UnmarshalEvent([]string{"CHAN_START"}, &v)
+	is.Equal(fmt.Sprint(err), "failed to map field AppData: index 11 out of range for record of length 1")
+}
+
+func TestUnmarshalEventJoinedColumns(t *testing.T) {
+	is := is.NewRelaxed(t)
+	var v struct {
+		Time      time.Time `cel:"1+2,layout=2006-01-02 15:04:05"`
+		Separated time.Time `cel:"1+2,sep=T,layout=2006-01-02T15:04:05"`
+		Joined    string    `cel:"2+0+1,sep=/"`
+	}
+	is.NoErr(cel.UnmarshalEvent([]string{"CHAN_START", "2018-07-05", "12:45:00"}, &v))
+	is.Equal(v.Time, time.Date(2018, 7, 5, 12, 45, 0, 0, time.UTC))
+	is.Equal(v.Separated, v.Time)
+	is.Equal(v.Joined, "12:45:00/CHAN_START/2018-07-05")
+
+	err := cel.UnmarshalEvent([]string{"CHAN_START", "2018-07-05"}, &v)
+	is.Equal(fmt.Sprint(err), "failed to map field Time: index 2 out of range for record of length 2")
+
+	var bad struct {
+		Time time.Time `cel:"1+x"`
+	}
+	err = cel.UnmarshalEvent([]string{"CHAN_START", "2018-07-05"}, &bad)
+	is.Equal(fmt.Sprint(err), `failed to map field Time: bad tag value "1+x": strconv.ParseInt: parsing "x": invalid syntax`)
+}
+
+func TestUnmarshalEventSecOfDay(t *testing.T) {
+	is := is.NewRelaxed(t)
+	var v struct {
+		OnEventDate time.Time     `cel:"2,secofday"`
+		OnDate      time.Time     `cel:"2,secofday=0"`
+		Duration    time.Duration `cel:"2,secofday"`
+	}
+	is.NoErr(cel.UnmarshalEvent([]string{"1530794700.5", "1530794700.5", "3600"}, &v))
+	is.True(v.OnEventDate.Equal(time.Date(2018, 7, 5, 1, 0, 0, 0, time.Local)))
+	is.Equal(v.OnDate, v.OnEventDate)
+	is.Equal(v.Duration, time.Hour)
+
+	is.NoErr(cel.UnmarshalEvent([]string{"", "", "90000"}, &v))
+	is.Equal(v.OnEventDate, time.Date(1, 1, 2, 1, 0, 0, 0, time.UTC))
+	is.Equal(v.OnDate, v.OnEventDate)
+	is.Equal(v.Duration, 25*time.Hour)
+
+	// Without a usable eventtime, the default base is the zero date.
+	for _, record := range [][]string{{"", "soon", "3600"}, {"", "", "3600"}, {"", "3600"}, {"3600"}} {
+		tag := fmt.Sprintf(`cel:"%d,secofday"`, len(record)-1) // the last column
+		rv := reflect.New(reflect.StructOf([]reflect.StructField{{Name: "Time", Type: reflect.TypeOf(time.Time{}), Tag: reflect.StructTag(tag)}}))
+		is.NoErr(cel.UnmarshalEvent(record, rv.Interface()))
+		is.Equal(rv.Elem().Field(0).Interface(), time.Date(1, 1, 1, 1, 0, 0, 0, time.UTC))
+	}
+
+	err := cel.UnmarshalEvent([]string{"", "", "-1"}, &v)
+	is.Equal(fmt.Sprint(err), "failed to map field OnEventDate: seconds of day -1 is negative")
+	err = cel.UnmarshalEvent([]string{"", "", "01:00"}, &v)
+	is.Equal(fmt.Sprint(err), `failed to map field OnEventDate: unable to convert field value "01:00" to seconds of day: strconv.ParseInt: parsing "01:00": invalid syntax`)
+	err = cel.UnmarshalEvent([]string{"soon", "soon", "3600"}, &v)
+	is.True(strings.HasPrefix(fmt.Sprint(err), `failed to map field OnDate: bad secofday base: unable to convert field value "soon" to time.Time`))
+
+	var bad struct {
+		Seconds int `cel:"0,secofday"`
+	}
+	err = cel.UnmarshalEvent([]string{"3600"}, &bad)
+	is.Equal(fmt.Sprint(err), "failed to map field Seconds: secofday needs a time.Time or time.Duration field, not int")
+
+	record, err := cel.MarshalEvent(struct {
+		Time     time.Time     `cel:"0,secofday"`
+		Duration time.Duration `cel:"1,secofday"`
+	}{time.Date(2018, 7, 5, 1, 0, 30, 0, time.UTC), time.Hour})
+	is.NoErr(err)
+	is.Equal(record, []string{"3630", "3600"})
+
+	// Days past the base date round-trip.
+	type event struct {
+		Time  *time.Time `cel:"1"`
+		Start time.Time  `cel:"2,secofday"`
+		Other time.Time  `cel:"3,secofday=1"`
+	}
+	for _, in := range [][]string{{"", "1530794700.500000", "90000", "90000"}, {"", "", "90000", "90000"}} {
+		var e event
+		is.NoErr(cel.UnmarshalEvent(in, &e))
+		record, err := cel.MarshalEvent(e)
+		is.NoErr(err)
+		is.Equal(record, in)
+	}
+}
+
+func TestUnmarshalEventSlice(t *testing.T) {
+	is := is.NewRelaxed(t)
+	var v struct {
+		Times []time.Time `cel:"0"`
+		Ints  []int       `cel:"1,trim"`
+	}
+	is.NoErr(cel.UnmarshalEvent([]string{"1530794700.5|1530794730||1530794760.000001", "1|2"}, &v))
+	is.Equal(v.Times, []time.Time{time.Unix(1530794700, 500000000), time.Unix(1530794730, 0), time.Unix(1530794760, 1000)})
+	is.Equal(v.Ints, []int{1, 2})
+	is.NoErr(cel.UnmarshalEvent([]string{"", " "}, &v))
+	is.Equal(v.Times, nil)
+	is.Equal(v.Ints, nil)
+
+	err := cel.UnmarshalEvent([]string{"1530794700|.5", ""}, &v)
+	is.Equal(fmt.Sprint(err), `failed to map field Times: element 1: unable to convert field value ".5" to time.Time: missing seconds part`)
+
+	record, err := cel.MarshalEvent(struct {
+		Times []time.Time `cel:"0"`
+	}{[]time.Time{time.Unix(1530794700, 500000000), time.Unix(1530794730, 0)}})
+	is.NoErr(err)
+	is.Equal(record, []string{"1530794700.500000|1530794730.000000"})
+}
+
+func TestUnmarshalEventStructOf(t *testing.T) {
+	is := is.NewRelaxed(t)
+	typ := reflect.StructOf([]reflect.StructField{
+		{Name: "Type", Type: reflect.TypeOf(cel.EventType("")), Tag: `cel:"0"`},
+		{Name: "Time", Type: reflect.TypeOf(&time.Time{}), Tag: `cel:"1"`},
+		{Name: "Extra", Type: reflect.TypeOf(map[string]string{}), Tag: `cel:"2,json"`},
+		{Name: "Untagged", Type: reflect.TypeOf("")},
+	})
+	record := []string{"HANGUP", "1530794700.5", `{"dialstatus":"ANSWER"}`}
+
+	v := reflect.New(typ)
+	is.NoErr(cel.UnmarshalEvent(record, v.Interface()))
+	is.Equal(v.Elem().Field(0).Interface(), cel.Hangup)
+	is.True(v.Elem().Field(1).Interface().(*time.Time).Equal(time.Unix(1530794700, 500000000)))
+	is.Equal(v.Elem().Field(2).Interface(), map[string]string{"dialstatus": "ANSWER"})
+	is.Equal(v.Elem().Field(3).Interface(), "")
+
+	out, err := cel.MarshalEvent(v.Interface())
+	is.NoErr(err)
+	is.Equal(out, []string{"HANGUP", "1530794700.500000", `{"dialstatus":"ANSWER"}`})
+
+	slice := reflect.New(reflect.SliceOf(typ))
+	in := "HANGUP,1530794700.5,{}\nANSWER,1530794701,{}\n"
+	is.NoErr(cel.NewDecoder(strings.NewReader(in)).DecodeAll(slice.Interface()))
+	is.Equal(slice.Elem().Len(), 2)
+	is.Equal(slice.Elem().Index(1).Field(0).Interface(), cel.Answer)
+
+	err = cel.UnmarshalEvent(record, reflect.New(typ).Elem().Interface())
+	is.True(strings.HasPrefix(fmt.Sprint(err), "cel: UnmarshalEvent(non-pointer struct {"))
+}
+
+func TestUnmarshalEventSubCSV(t *testing.T) {
+	is := is.NewRelaxed(t)
+	type agent struct {
+		Name  string `cel:"0"`
+		Phone string `cel:"1"`
+	}
+	type queue struct {
+		Name  string `cel:"0"`
+		Wait  int    `cel:"1"`
+		Agent agent  `cel:"2,subcsv=/"`
+	}
+	type event struct {
+		Type  string `cel:"0"`
+		Queue *queue `cel:"1,subcsv=;"`
+	}
+	var v event
+	record := []string{"APP_END", `sales;42;"Alice/1001"`}
+	is.NoErr(cel.UnmarshalEvent(record, &v))
+	is.Equal(v, event{"APP_END", &queue{"sales", 42, agent{"Alice", "1001"}}})
+
+	marshaled, err := cel.MarshalEvent(v)
+	is.NoErr(err)
+	is.Equal(marshaled, []string{"APP_END", "sales;42;Alice/1001"})
+
+	is.NoErr(cel.UnmarshalEvent([]string{"APP_END", ""}, &v))
+	is.Equal(v.Queue, nil)
+
+	err = cel.UnmarshalEvent([]string{"APP_END", "sales;soon;a/b"}, &v)
+	is.Equal(fmt.Sprint(err), `failed to map field Queue: failed to map field Wait: unable to convert field value "soon" to int: strconv.ParseInt: parsing "soon": invalid syntax`)
+
+	var bad struct {
+		Queue queue `cel:"1,subcsv=;;"`
+	}
+	err = cel.UnmarshalEvent(record, &bad)
+	is.Equal(fmt.Sprint(err), `failed to map field Queue: bad subcsv option ";;": expected a single character`)
+}
+
+func TestUnmarshalEventJSONStringMap(t *testing.T) {
+	is := is.NewRelaxed(t)
+	var v struct {
+		Extra map[string]string `cel:"0,json"`
+	}
+	extra := `{"hangupcause": 16, "hangupsource": "SIP/1001-01", "dialstatus": null, "answered": true, "codecs": ["alaw", "ulaw"], "bridge": {"id": "b1"}}`
+	is.NoErr(cel.UnmarshalEvent([]string{extra}, &v))
+	is.Equal(v.Extra, map[string]string{
+		"hangupcause":  "16",
+		"hangupsource": "SIP/1001-01",
+		"dialstatus":   "",
+		"answered":     "true",
+		"codecs":       `["alaw","ulaw"]`,
+		"bridge":       `{"id":"b1"}`,
+	})
+
+	err := cel.UnmarshalEvent([]string{`["not", "an", "object"]`}, &v)
+	is.True(strings.HasPrefix(fmt.Sprint(err), "failed to map field Extra: json: cannot unmarshal array"))
+}
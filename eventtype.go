@@ -0,0 +1,71 @@
+package cel
+
+// EventType is the type of a CEL event.
+type EventType string
+
+// The event types that Asterisk logs.
+const (
+	ChanStart        EventType = "CHAN_START"
+	ChanEnd          EventType = "CHAN_END"
+	Answer           EventType = "ANSWER"
+	Hangup           EventType = "HANGUP"
+	AppStart         EventType = "APP_START"
+	AppEnd           EventType = "APP_END"
+	ParkStart        EventType = "PARK_START"
+	ParkEnd          EventType = "PARK_END"
+	UserDefined      EventType = "USER_DEFINED"
+	BridgeEnter      EventType = "BRIDGE_ENTER"
+	BridgeExit       EventType = "BRIDGE_EXIT"
+	BlindTransfer    EventType = "BLINDTRANSFER"
+	AttendedTransfer EventType = "ATTENDEDTRANSFER"
+	Pickup           EventType = "PICKUP"
+	Forward          EventType = "FORWARD"
+	LinkedIDEnd      EventType = "LINKEDID_END"
+	LocalOptimize    EventType = "LOCAL_OPTIMIZE"
+)
+
+// eventTypeCodes holds the numeric codes that Asterisk uses for the event
+// types internally (enum ast_cel_event_type).
+var eventTypeCodes = map[EventType]int{
+	ChanStart:        1,
+	ChanEnd:          2,
+	Hangup:           3,
+	Answer:           4,
+	AppStart:         5,
+	AppEnd:           6,
+	ParkStart:        7,
+	ParkEnd:          8,
+	UserDefined:      9,
+	BridgeEnter:      10,
+	BridgeExit:       11,
+	BlindTransfer:    12,
+	AttendedTransfer: 13,
+	Pickup:           14,
+	Forward:          15,
+	LinkedIDEnd:      16,
+	LocalOptimize:    17,
+}
+
+// Code returns the numeric code that Asterisk uses for event type t, as in
+// 1 for CHAN_START, or -1 if t is not one of the event types above.
+func (t EventType) Code() int {
+	if code, ok := eventTypeCodes[t]; ok {
+		return code
+	}
+	return -1
+}
+
+// HistogramTotal is the key under which EventTypeHistogram stores the total
+// number of events.
+const HistogramTotal = "total"
+
+// EventTypeHistogram returns the number of events of each type, keyed by the
+// event type (for instance string(ChanStart)), and the total number of events
+// under the HistogramTotal key.
+func EventTypeHistogram(events []Event) map[string]int {
+	h := map[string]int{HistogramTotal: len(events)}
+	for _, e := range events {
+		h[string(e.Type)]++
+	}
+	return h
+}
@@ -0,0 +1,37 @@
+package cel_test
+
+import (
+	"testing"
+
+	"github.com/VoIPGRID/cel"
+	"github.com/matryer/is"
+)
+
+func TestEventTypeHistogram(t *testing.T) {
+	is := is.NewRelaxed(t)
+	events := []cel.Event{
+		{Type: cel.ChanStart, LinkedID: "1.1"},
+		{Type: cel.ChanStart, LinkedID: "1.1"},
+		{Type: cel.Answer, LinkedID: "1.1"},
+		{Type: cel.Hangup, LinkedID: "1.1"},
+		{Type: cel.ChanStart, LinkedID: "2.1"},
+	}
+	is.Equal(cel.EventTypeHistogram(events), map[string]int{
+		string(cel.ChanStart): 3,
+		string(cel.Answer):    1,
+		string(cel.Hangup):    1,
+		cel.HistogramTotal:    5,
+	})
+	is.Equal(cel.EventTypeHistogram(nil), map[string]int{cel.HistogramTotal: 0})
+
+	calls := cel.GroupByLinkedID(events)
+	is.Equal(calls[1].EventCounts(), map[string]int{string(cel.ChanStart): 1, cel.HistogramTotal: 1})
+}
+
+func TestEventTypeCode(t *testing.T) {
+	is := is.NewRelaxed(t)
+	is.Equal(cel.ChanStart.Code(), 1)
+	is.Equal(cel.Answer.Code(), 4)
+	is.Equal(cel.LocalOptimize.Code(), 17)
+	is.Equal(cel.EventType("CUSTOM").Code(), -1)
+}
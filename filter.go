@@ -0,0 +1,14 @@
+package cel
+
+// FilterDecoder makes dec skip records for which keep returns false. It
+// modifies and returns dec, so it composes with other wrappers such as Dedup.
+//
+// keep is called with each record as it is split from the line, before the
+// record is reordered (see WithRemap) and unmarshaled, so it is cheap even
+// for records that are skipped. Wrappers are evaluated in the order in which
+// they were applied to dec, and a record that one of them skips is not passed
+// to the ones after it. keep must not retain or modify the record.
+func FilterDecoder(dec *Decoder, keep func(record []string) bool) *Decoder {
+	dec.filters = append(dec.filters, keep)
+	return dec
+}
@@ -0,0 +1,51 @@
+package cel_test
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/VoIPGRID/cel"
+	"github.com/matryer/is"
+)
+
+func TestFilterDecoder(t *testing.T) {
+	is := is.NewRelaxed(t)
+	in := strings.Join([]string{
+		"CHAN_START,1530794700.5",
+		"ANSWER,1530794705",
+		"ANSWER,1530794705",
+		"HANGUP,1530794730",
+		"CHAN_END,1530794731",
+		"LINKEDID_END,soon",
+	}, "\n")
+	window := func(from, to float64) func(record []string) bool {
+		return func(record []string) bool {
+			sec, err := strconv.ParseFloat(record[1], 64)
+			return err == nil && sec >= from && sec < to
+		}
+	}
+	var calls int
+	counter := func(record []string) bool {
+		calls++
+		return true
+	}
+	dec := cel.NewDecoder(strings.NewReader(in))
+	dec = cel.FilterDecoder(cel.Dedup(cel.FilterDecoder(dec, window(1530794701, 1530794731)), 10), counter)
+
+	var got []string
+	for {
+		var v struct {
+			Type string `cel:"0"`
+		}
+		err := dec.Decode(&v)
+		if err == io.EOF {
+			break
+		}
+		is.NoErr(err)
+		got = append(got, v.Type)
+	}
+	is.Equal(got, []string{"ANSWER", "HANGUP"})
+	is.Equal(calls, 2) // only records kept by the earlier wrappers
+}
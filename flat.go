@@ -0,0 +1,74 @@
+package cel
+
+import (
+	"time"
+)
+
+// A FlatEvent is a flattened Event, with only scalar types that columnar
+// storage formats (such as Parquet) handle directly. The representation is
+// stable: fields are only ever added.
+//
+// None of the fields are nullable. Absent values are represented by the zero
+// value of their type: empty strings for text, and 0 for times and codes.
+type FlatEvent struct {
+	EventType     int32  // See EventType.Code; -1 for unknown types.
+	EventTypeName string // The event type as logged, as in "CHAN_START".
+	EventTime     int64  // Microseconds since the Unix epoch; 0 for a zero time.
+
+	CIDName     string
+	CIDNum      string
+	CIDANI      string
+	CIDRDNIS    string
+	CIDDNID     string
+	Exten       string
+	Context     string
+	ChanName    string
+	AppName     string
+	AppData     string
+	AMAFlags    int32 // The standard code of the AMA flags.
+	AccountCode string
+	UniqueID    string
+	LinkedID    string
+	Peer        string
+	UserField   string
+	UserDefType string
+	Extra       string
+	PeerAccount string
+}
+
+// Flatten returns e as a FlatEvent.
+func (e Event) Flatten() FlatEvent {
+	return FlatEvent{
+		EventType:     int32(e.Type.Code()),
+		EventTypeName: string(e.Type),
+		EventTime:     unixMicro(e.Time),
+		CIDName:       e.CIDName,
+		CIDNum:        e.CIDNum,
+		CIDANI:        e.CIDANI,
+		CIDRDNIS:      e.CIDRDNIS,
+		CIDDNID:       e.CIDDNID,
+		Exten:         e.Exten,
+		Context:       e.Context,
+		ChanName:      e.ChanName,
+		AppName:       e.AppName,
+		AppData:       e.AppData,
+		AMAFlags:      int32(e.AMAFlags),
+		AccountCode:   e.AccountCode,
+		UniqueID:      e.UniqueID,
+		LinkedID:      e.LinkedID,
+		Peer:          e.Peer,
+		UserField:     e.UserField,
+		UserDefType:   e.UserDefType,
+		Extra:         e.Extra,
+		PeerAccount:   e.PeerAccount,
+	}
+}
+
+// unixMicro returns t as microseconds since the Unix epoch, or 0 for the
+// zero time.
+func unixMicro(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()*1e6 + int64(t.Nanosecond()/1e3)
+}
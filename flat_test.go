@@ -0,0 +1,41 @@
+package cel_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/VoIPGRID/cel"
+	"github.com/matryer/is"
+)
+
+func TestEventFlatten(t *testing.T) {
+	is := is.NewRelaxed(t)
+	e := cel.NewEventBuilder().
+		Type(cel.Hangup).
+		At(time.Unix(1530794730, 987654321)).
+		CIDNum("1001").
+		AccountCode("acc1").
+		Extra(`{"dialstatus":"ANSWER"}`).
+		Event()
+	e.PeerAccount = "acc2"
+	is.Equal(e.Flatten(), cel.FlatEvent{
+		EventType:     3,
+		EventTypeName: "HANGUP",
+		EventTime:     1530794730987654,
+		CIDNum:        "1001",
+		Context:       "default",
+		ChanName:      "SIP/1001-00000001",
+		AMAFlags:      3,
+		AccountCode:   "acc1",
+		UniqueID:      "1530794730.1",
+		LinkedID:      "1530794730.1",
+		Extra:         `{"dialstatus":"ANSWER"}`,
+		PeerAccount:   "acc2",
+	})
+
+	flat := cel.Event{Type: "CUSTOM", AMAFlags: cel.AMAFlagsBilling}.Flatten()
+	is.Equal(flat.EventType, int32(-1))
+	is.Equal(flat.EventTypeName, "CUSTOM")
+	is.Equal(flat.EventTime, int64(0))
+	is.Equal(flat.AMAFlags, int32(2))
+}
@@ -0,0 +1,177 @@
+package cel
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DecodeJSON reads a JSON array of objects from r, and appends a struct for
+// each object to the slice that slicePtr points to. slicePtr must be a
+// pointer to a slice of structs, or of pointers to structs, whose fields are
+// tagged as for UnmarshalEvent.
+//
+// Fields are looked up by key rather than by position: a tag with index N
+// uses the key that is the name of column N of the stock layout (as in
+// "eventtype" for 0, or "extra" for 19), and a tag with a column name uses the
+// key with that name. This allows the same struct to decode both CSV and JSON.
+// Missing keys and null values are treated as empty strings. Strings are used
+// as they are; other values (numbers, objects) are used as JSON text, so an
+// object can be decoded using ",json".
+func DecodeJSON(r io.Reader, slicePtr interface{}) error {
+	t, err := newSliceTarget(slicePtr)
+	if err != nil {
+		return fmt.Errorf("cel: DecodeJSON(%v): %v", reflect.TypeOf(slicePtr), err)
+	}
+	var objects []map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&objects); err != nil {
+		return err
+	}
+	names := columnNames(t.structType)
+	for i, obj := range objects {
+		record, columns, err := jsonRecord(obj, names)
+		if err != nil {
+			return errors.Wrapf(err, "element %d", i)
+		}
+		v := reflect.New(t.structType)
+		if err := unmarshalEvent(record, v.Interface(), &config{columns: columns}, nil); err != nil {
+			return errors.Wrapf(err, "element %d", i)
+		}
+		t.append(v)
+	}
+	return nil
+}
+
+// jsonRecord turns obj into a record with the standard columns first,
+// followed by any other keys. columns maps every key, as well as every name in
+// names, to its index.
+func jsonRecord(obj map[string]json.RawMessage, names []string) (record []string, columns map[string]int, err error) {
+	columns = make(map[string]int, len(obj))
+	for i, name := range standardColumns {
+		columns[name] = i
+	}
+	record = make([]string, len(standardColumns))
+	for k, raw := range obj {
+		s, err := jsonString(raw)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "key %q", k)
+		}
+		i, ok := columns[k]
+		if !ok {
+			i = len(record)
+			columns[k] = i
+			record = append(record, "")
+		}
+		record[i] = s
+	}
+	for _, name := range names {
+		if _, ok := columns[name]; !ok {
+			columns[name] = len(record)
+			record = append(record, "")
+		}
+	}
+	return record, columns, nil
+}
+
+// columnNames returns the column names used in the cel tags of struct type t.
+func columnNames(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("cel"), ",")[0]
+		if _, err := strconv.Atoi(name); name != "" && err != nil && !strings.Contains(name, "+") {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func jsonString(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", nil
+	}
+	if raw[0] == '"' {
+		var s string
+		err := json.Unmarshal(raw, &s)
+		return s, err
+	}
+	return string(raw), nil
+}
+
+// RecordToJSON returns record, in the stock layout, as a JSON object keyed by
+// the names of its columns (see StandardColumns), in column order. It is the
+// counterpart of DecodeJSON for records that are not decoded into a struct.
+//
+// Values are JSON strings, except for eventtime, which is converted to an
+// RFC 3339 time in UTC (with fractional seconds if it has them), and extra,
+// which is nested as it is if it holds a valid JSON value. An empty eventtime
+// is kept as an empty string. Columns beyond extra are ignored, and columns
+// missing from short records are left out.
+func RecordToJSON(record []string) ([]byte, error) {
+	return recordToJSON(record, &config{})
+}
+
+func recordToJSON(record []string, c *config) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, s := range record {
+		if i >= len(standardColumns) {
+			break
+		}
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(standardColumns[i])
+		b.Write(key)
+		b.WriteByte(':')
+		switch {
+		case i == ColEventTime && s != "":
+			var t time.Time
+			if err := convertTime(reflect.ValueOf(&t).Elem(), s, []string{""}, c); err != nil {
+				return nil, errors.Wrapf(err, "column %s", standardColumns[i])
+			}
+			s = t.UTC().Format(time.RFC3339Nano)
+		case i == ColExtra && s != "" && json.Valid([]byte(s)):
+			if err := json.Compact(&b, []byte(s)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		value, _ := json.Marshal(s)
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// WriteJSONLines is the streaming variant of RecordToJSON: it reads the
+// remaining records from d and writes each to w as a JSON object on a line
+// of its own (the JSON Lines format). Event times are parsed using the
+// options of d, such as WithTimeLayouts and WithEpochBase. It returns nil at
+// the end of the input, or the first error, which includes the line number
+// for records that fail to convert.
+func WriteJSONLines(w io.Writer, d *Decoder) error {
+	for {
+		record, err := d.readRecord()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		d.records++
+		b, err := recordToJSON(record, &d.cfg)
+		if err != nil {
+			return errors.Wrapf(err, "line %d", d.line)
+		}
+		if _, err := w.Write(append(b, '\n')); err != nil {
+			return err
+		}
+	}
+}
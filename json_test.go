@@ -0,0 +1,98 @@
+package cel_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VoIPGRID/cel"
+	"github.com/matryer/is"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	is := is.NewRelaxed(t)
+	in := `[
+		{"eventtype": "CHAN_START", "eventtime": 1530794700.5, "channame": "SIP/1001-01", "uniqueid": "1.1", "linkedid": "1.1", "extra": null},
+		{"eventtype": "HANGUP", "eventtime": "1530794730", "uniqueid": "1.1", "linkedid": "1.1", "peeraccount": "acc2",
+		 "extra": {"hangupcause": 16, "dialstatus": "ANSWER"}, "server": "pbx1"}
+	]`
+	var events []cel.Event
+	is.NoErr(cel.DecodeJSON(strings.NewReader(in), &events))
+	is.Equal(len(events), 2)
+	is.Equal(events[0].Type, cel.ChanStart)
+	is.Equal(events[0].Time, time.Unix(1530794700, 500000000))
+	is.Equal(events[0].ChanName, "SIP/1001-01")
+	is.Equal(events[0].Extra, "")
+	is.Equal(events[1].Type, cel.Hangup)
+	is.Equal(events[1].PeerAccount, "acc2")
+	is.Equal(events[1].Extra, `{"hangupcause": 16, "dialstatus": "ANSWER"}`)
+
+	var custom []struct {
+		Type   cel.EventType `cel:"eventtype"`
+		Server string        `cel:"server"`
+		Extra  struct {
+			HangupCause int `json:"hangupcause"`
+		} `cel:"extra,json,noerror"`
+	}
+	is.NoErr(cel.DecodeJSON(strings.NewReader(in), &custom))
+	is.Equal(custom[0].Server, "")
+	is.Equal(custom[1].Type, cel.Hangup)
+	is.Equal(custom[1].Server, "pbx1")
+	is.Equal(custom[1].Extra.HangupCause, 16)
+
+	err := cel.DecodeJSON(strings.NewReader(`[{"eventtime": "soon"}]`), &events)
+	is.Equal(fmt.Sprint(err), `element 0: failed to map field Time: unable to convert field value "soon" to time.Time: strconv.ParseInt: parsing "soon": invalid syntax`)
+	err = cel.DecodeJSON(strings.NewReader(`[]`), events)
+	is.Equal(fmt.Sprint(err), "cel: DecodeJSON([]cel.Event): not a pointer to a slice")
+
+	var pointers []*cel.Event
+	is.NoErr(cel.DecodeJSON(strings.NewReader(in), &pointers))
+	is.Equal(len(pointers), 2)
+	is.Equal(*pointers[0], events[0])
+}
+
+func TestRecordToJSON(t *testing.T) {
+	is := is.NewRelaxed(t)
+	record := cel.NewEventBuilder().Type(cel.Hangup).CIDNum("1001").Extra(`{"hangupcause": 16, "dialstatus": "ANSWER"}`).Record()
+	record[cel.ColEventTime] = "1530794700.5"
+	b, err := cel.RecordToJSON(record)
+	is.NoErr(err)
+	is.Equal(string(b), `{"eventtype":"HANGUP","eventtime":"2018-07-05T12:45:00.5Z","cid_name":"","cid_num":"1001",`+
+		`"cid_ani":"","cid_rdnis":"","cid_dnid":"","exten":"","context":"default","channame":"SIP/1001-00000001",`+
+		`"appname":"","appdata":"","amaflags":"3","accountcode":"","uniqueid":"1530794700.1","linkedid":"1530794700.1",`+
+		`"peer":"","userfield":"","userdeftype":"","extra":{"hangupcause":16,"dialstatus":"ANSWER"}}`)
+
+	cases := []struct {
+		in   []string
+		want string
+		err  string
+	}{
+		{[]string{"CHAN_START", ""}, `{"eventtype":"CHAN_START","eventtime":""}`, ""},
+		{append(make([]string, 19), "not json"), `{"eventtype":"","eventtime":"","cid_name":"","cid_num":"","cid_ani":"","cid_rdnis":"","cid_dnid":"","exten":"","context":"","channame":"","appname":"","appdata":"","amaflags":"","accountcode":"","uniqueid":"","linkedid":"","peer":"","userfield":"","userdeftype":"","extra":"not json"}`, ""},
+		{[]string{"CHAN_START", "soon"}, "", `column eventtime: unable to convert field value "soon" to time.Time: strconv.ParseInt: parsing "soon": invalid syntax`},
+	}
+	for _, c := range cases {
+		b, err := cel.RecordToJSON(c.in)
+		if c.err != "" {
+			is.Equal(fmt.Sprint(err), c.err)
+			continue
+		}
+		is.NoErr(err)
+		is.Equal(string(b), c.want)
+	}
+}
+
+func TestWriteJSONLines(t *testing.T) {
+	is := is.NewRelaxed(t)
+	in := "\"CHAN_START\",\"1530794700\",\"Alice\"\n\n\"CHAN_END\",\"1530794730\"\n"
+	var b strings.Builder
+	is.NoErr(cel.WriteJSONLines(&b, cel.NewDecoder(strings.NewReader(in))))
+	is.Equal(b.String(), `{"eventtype":"CHAN_START","eventtime":"2018-07-05T12:45:00Z","cid_name":"Alice"}`+"\n"+
+		`{"eventtype":"CHAN_END","eventtime":"2018-07-05T12:45:30Z"}`+"\n")
+
+	b.Reset()
+	err := cel.WriteJSONLines(&b, cel.NewDecoder(strings.NewReader(in+`"HANGUP","x"`)))
+	is.True(strings.HasPrefix(fmt.Sprint(err), `line 4: column eventtime: unable to convert field value "x"`))
+	is.Equal(strings.Count(b.String(), "\n"), 2)
+}
@@ -0,0 +1,71 @@
+package cel
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// A LazyRecord is a record that has been read by a Decoder, but not yet
+// unmarshaled. It allows inspecting single columns cheaply, for instance to
+// reject most records before paying for a full Unmarshal.
+//
+// A LazyRecord does not share memory with its Decoder: the Decoder allocates
+// a new record for each line, so a LazyRecord stays valid, and may be kept,
+// after later calls to the Decoder. It does use the settings of the Decoder,
+// which must not be changed while the LazyRecord is in use.
+type LazyRecord struct {
+	dec    *Decoder
+	record []string
+	line   int
+}
+
+// DecodeLazy reads the next record, like Decode, but returns it without
+// unmarshaling it. Returns io.EOF at the end of the input. The decode hook
+// (see WithDecodeHook) is not called for lazy records.
+func (d *Decoder) DecodeLazy() (*LazyRecord, error) {
+	record, err := d.readRecord()
+	if err != nil {
+		return nil, err
+	}
+	d.records++
+	return &LazyRecord{dec: d, record: record, line: d.line}, nil
+}
+
+// Line returns the number of the line the record was read from.
+func (r *LazyRecord) Line() int {
+	return r.line
+}
+
+// Get converts the value of the column at index into target, which must be a
+// pointer to any of the field types that UnmarshalEvent supports, with the
+// settings of the Decoder (such as WithRemap and WithTimeLayouts). Tag
+// options are not available; use Unmarshal for those.
+func (r *LazyRecord) Get(index int, target interface{}) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("cel: Get(%v): not a pointer", reflect.TypeOf(target))
+	}
+	convert := converterFor(v.Elem().Type())
+	if convert == nil {
+		return fmt.Errorf("cel: Get(%v): type %s not implemented", reflect.TypeOf(target), v.Elem().Type())
+	}
+	if r.dec.remap != nil {
+		if index < 0 || index >= len(r.dec.remap) {
+			return errors.Errorf("line %d: remap table has %d columns, but index %d was requested", r.line, len(r.dec.remap), index)
+		}
+		index = r.dec.remap[index]
+	}
+	if index < 0 || index >= len(r.record) {
+		return errors.Wrapf(&missingColumnError{index, len(r.record)}, "line %d", r.line)
+	}
+	cfg := r.dec.cfg
+	cfg.line = r.line
+	return errors.Wrapf(convert(v.Elem(), r.record[index], []string{""}, &cfg), "line %d", r.line)
+}
+
+// Unmarshal unmarshals the whole record into v, as Decode would have.
+func (r *LazyRecord) Unmarshal(v interface{}) error {
+	return errors.Wrapf(r.dec.unmarshal(r.record, v, r.line), "line %d", r.line)
+}
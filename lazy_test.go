@@ -0,0 +1,85 @@
+package cel_test
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VoIPGRID/cel"
+	"github.com/matryer/is"
+)
+
+func TestDecoderDecodeLazy(t *testing.T) {
+	is := is.NewRelaxed(t)
+	in := celLine(cel.ChanStart, 0, "1.1", "1.1") + "\n" +
+		`"HANGUP","1530794730"` + "\n"
+	dec := cel.NewDecoder(strings.NewReader(in))
+
+	first, err := dec.DecodeLazy()
+	is.NoErr(err)
+	second, err := dec.DecodeLazy()
+	is.NoErr(err)
+	_, err = dec.DecodeLazy()
+	is.Equal(err, io.EOF)
+
+	// Records stay valid after later calls.
+	var typ cel.EventType
+	is.NoErr(first.Get(cel.ColEventType, &typ))
+	is.Equal(typ, cel.ChanStart)
+	var at *time.Time
+	is.NoErr(first.Get(cel.ColEventTime, &at))
+	is.True(at.Equal(time.Unix(1530794700, 0)))
+	var e cel.Event
+	is.NoErr(first.Unmarshal(&e))
+	is.Equal(e.ChanName, "SIP/1.1")
+	is.Equal(first.Line(), 1)
+
+	var n int
+	is.Equal(fmt.Sprint(second.Get(cel.ColEventType, &n)), `line 3: unable to convert field value "HANGUP" to int: strconv.ParseInt: parsing "HANGUP": invalid syntax`)
+	is.Equal(fmt.Sprint(second.Get(cel.ColChanName, &typ)), "line 3: index 9 out of range for record of length 2")
+	is.Equal(fmt.Sprint(second.Get(0, typ)), "cel: Get(cel.EventType): not a pointer")
+	is.Equal(fmt.Sprint(second.Unmarshal(&e)), "line 3: failed to map field CIDName: index 2 out of range for record of length 2")
+
+	dec = cel.NewDecoder(strings.NewReader("a,b\n"), cel.WithRemap([]int{1, 0}))
+	r, err := dec.DecodeLazy()
+	is.NoErr(err)
+	var s string
+	is.NoErr(r.Get(0, &s))
+	is.Equal(s, "b")
+	is.Equal(fmt.Sprint(r.Get(2, &s)), "line 1: remap table has 2 columns, but index 2 was requested")
+}
+
+var benchmarkInput = strings.Repeat(`"CHAN_START","1530794700.5","Alice","1001","1001","","","2001","from-internal","SIP/1001-00000001","","","3","acc1","1.1","1.1","","","",""`+"\n"+
+	`"HANGUP","1530794730.5","Alice","1001","1001","","","2001","from-internal","SIP/1001-00000001","","","3","acc1","1.1","1.1","","","","{""hangupcause"":16}"`+"\n", 500)
+
+func BenchmarkDecodeEager(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		dec := cel.NewDecoder(strings.NewReader(benchmarkInput))
+		for {
+			var e cel.Event
+			if err := dec.Decode(&e); err != nil {
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkDecodeLazy(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		dec := cel.NewDecoder(strings.NewReader(benchmarkInput))
+		for {
+			r, err := dec.DecodeLazy()
+			if err != nil {
+				break
+			}
+			var typ cel.EventType
+			if r.Get(cel.ColEventType, &typ) != nil || typ != cel.Hangup {
+				continue
+			}
+			var e cel.Event
+			r.Unmarshal(&e)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package cel
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// MarshalEvent returns the record for struct v, or pointer to struct v. It is
+// the inverse of UnmarshalEvent: each field with a cel tag is formatted and
+// stored at the index of its tag. The record is as long as needed for the
+// highest index; columns without a field are empty.
+//
+// Values are formatted such that UnmarshalEvent, using the same opts and tags,
+// results in the same values. Times are written as Unix time with six
+// decimals (as Asterisk does, see also WithEpochBase), or using the ",layout"
+// of their tag. Zero times are written as "-", which UnmarshalEvent decodes
+// as a zero time (or a nil pointer), and nil pointers as empty strings.
+// Fields with ",json" are written using encoding/json.Marshal.
+//
+// Tags with column names are resolved using the WithColumn options in opts;
+// fields with unresolved names are skipped if they are ",optional". Fields
+// that join columns (as in `cel:"1+2"`) cannot be marshaled, and ",line"
+// fields are skipped.
+func MarshalEvent(v interface{}, opts ...Option) ([]string, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cel: MarshalEvent(%v): not a struct", reflect.TypeOf(v))
+	}
+	return marshalEvent(rv, &NewDecoder(nil, opts...).cfg)
+}
+
+func marshalEvent(rv reflect.Value, c *config) ([]string, error) {
+	var record []string
+	for i := 0; i < rv.NumField(); i++ {
+		f := rv.Type().Field(i)
+		tag := f.Tag.Get("cel")
+		if tag == "" || f.PkgPath != "" {
+			continue
+		}
+		tagParts := strings.Split(tag, ",")
+		if isLineTag(tagParts) {
+			continue
+		}
+		index, ok, err := marshalIndex(tagParts, c)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to marshal field %v", f.Name)
+		}
+		if !ok {
+			continue
+		}
+		var s string
+		if isSecOfDay(tagParts) && isTimeType(f.Type) {
+			s = formatSecOfDay(rv.Field(i).Interface().(time.Time), rv, tagParts, c)
+		} else if s, err = formatField(rv.Field(i), tagParts, c); err != nil {
+			return nil, errors.Wrapf(err, "failed to marshal field %v", f.Name)
+		}
+		for len(record) <= index {
+			record = append(record, "")
+		}
+		record[index] = s
+	}
+	return record, nil
+}
+
+// marshalIndex returns the index of the column that tagParts refers to. ok is
+// false if the field should be skipped.
+func marshalIndex(tagParts []string, c *config) (index int, ok bool, err error) {
+	if strings.Contains(tagParts[0], "+") {
+		return 0, false, errors.Errorf("cannot marshal joined columns %q", tagParts[0])
+	}
+	field, err := strconv.ParseInt(tagParts[0], 10, 0)
+	if err == nil {
+		return int(field), true, nil
+	}
+	index, ok = c.column(tagParts[0])
+	if !ok && !contains(tagParts, "optional") {
+		return 0, false, errors.Wrapf(err, "bad tag value %q", strings.Join(tagParts, ","))
+	}
+	return index, ok, nil
+}
+
+func formatField(v reflect.Value, tagParts []string, c *config) (string, error) {
+	if contains(tagParts, "json") {
+		b, err := json.Marshal(v.Interface())
+		return string(b), err
+	}
+	if _, ok := option(tagParts, "subcsv"); ok {
+		return formatSubRecord(v, tagParts, c)
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Slice {
+		parts := make([]string, v.Len())
+		for i := range parts {
+			var err error
+			if parts[i], err = formatField(v.Index(i), tagParts, c); err != nil {
+				return "", errors.Wrapf(err, "element %d", i)
+			}
+		}
+		return strings.Join(parts, sliceSeparator), nil
+	}
+	switch {
+	case v.Kind() == reflect.String:
+		return v.String(), nil
+	case isTimeType(v.Type()):
+		t := v.Interface().(time.Time)
+		if t.IsZero() {
+			return unknownMarker, nil
+		}
+		if layout, ok := option(tagParts, "layout"); ok {
+			return t.Format(layout), nil
+		}
+		if c.epochBase != nil {
+			t = time.Unix(t.Unix()-c.epochBase.Unix(), int64(t.Nanosecond()-c.epochBase.Nanosecond()))
+		}
+		return fmt.Sprintf("%d.%06d", t.Unix(), t.Nanosecond()/1000), nil
+	case v.Type() == amaFlagsType:
+		return formatAMAFlags(AMAFlags(v.Int()), c), nil
+	case v.Type() == durationType:
+		return formatDecimal(strconv.FormatFloat(time.Duration(v.Int()).Seconds(), 'f', -1, 64), c), nil
+	case isInt(v.Kind()):
+		return strconv.FormatInt(v.Int(), 10), nil
+	case isUint(v.Kind()):
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64:
+		return formatDecimal(strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), c), nil
+	case v.Kind() == reflect.Bool:
+		return c.boolFormat.format(v.Bool()), nil
+	}
+	return "", fmt.Errorf("type %s not implemented", v.Type())
+}
+
+// formatSecOfDay is the inverse of mapSecOfDay for times: it returns the
+// seconds from the midnight of the base date to t. The base date is taken
+// from the time field of struct rv that has the base column. Without one,
+// times on the zero date are relative to it, and other times to their own
+// midnight.
+func formatSecOfDay(t time.Time, rv reflect.Value, tagParts []string, c *config) string {
+	if t.IsZero() {
+		return unknownMarker
+	}
+	base, ok := secOfDayBase(rv, tagParts, c)
+	if !ok || t.Before(base) {
+		base = midnight(t)
+		if t.UTC().Year() == 1 {
+			base = zeroDate
+		}
+	}
+	return strconv.FormatInt(int64(t.Sub(base)/time.Second), 10)
+}
+
+// secOfDayBase returns the midnight of the time in the field of struct rv
+// that has the base column of a "secofday" field, if there is one and it is
+// not zero.
+func secOfDayBase(rv reflect.Value, tagParts []string, c *config) (time.Time, bool) {
+	index, ok := secOfDayBaseIndex(tagParts, c)
+	if !ok {
+		return time.Time{}, false
+	}
+	for i := 0; i < rv.NumField(); i++ {
+		f := rv.Type().Field(i)
+		parts := strings.Split(f.Tag.Get("cel"), ",")
+		if f.PkgPath != "" || isSecOfDay(parts) {
+			continue
+		}
+		if n, ok := columnIndex(parts[0], c); !ok || n != index {
+			continue
+		}
+		v := rv.Field(i)
+		if v.Kind() == reflect.Ptr && !v.IsNil() {
+			v = v.Elem()
+		}
+		if t, ok := v.Interface().(time.Time); ok && !t.IsZero() {
+			return midnight(t), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// formatSubRecord is the inverse of mapSubRecord.
+func formatSubRecord(v reflect.Value, tagParts []string, c *config) (string, error) {
+	r, err := subCSVDelimiter(tagParts)
+	if err != nil {
+		return "", err
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return "", fmt.Errorf("subcsv needs a struct, not %s", v.Type())
+	}
+	sub := *c
+	sub.columns = nil
+	record, err := marshalEvent(v, &sub)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	w.Comma = r
+	if err := w.Write(record); err != nil {
+		return "", err
+	}
+	w.Flush()
+	return strings.TrimSuffix(b.String(), "\n"), w.Error()
+}
+
+// formatDecimal is the inverse of decimalString.
+func formatDecimal(s string, c *config) string {
+	if c.decimalComma {
+		return strings.Replace(s, ".", ",", 1)
+	}
+	return s
+}
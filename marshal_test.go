@@ -0,0 +1,120 @@
+package cel_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VoIPGRID/cel"
+	"github.com/matryer/is"
+)
+
+func TestMarshalEvent(t *testing.T) {
+	is := is.NewRelaxed(t)
+	answer := time.Unix(1530794705, 0)
+	v := struct {
+		Type     cel.EventType  `cel:"0"`
+		Time     time.Time      `cel:"1"`
+		Day      time.Time      `cel:"2,layout=2006-01-02"`
+		Count    int            `cel:"4"`
+		Cost     float64        `cel:"5"`
+		Wait     time.Duration  `cel:"6"`
+		Answer   *time.Time     `cel:"7"`
+		Hangup   *time.Time     `cel:"8"`
+		JSON     map[string]int `cel:"9,json"`
+		Optional string         `cel:"missing,optional"`
+		ignored  string         `cel:"3"`
+	}{
+		Type:   cel.Hangup,
+		Time:   time.Unix(1530794700, 987654000),
+		Day:    time.Date(2018, 7, 5, 0, 0, 0, 0, time.UTC),
+		Count:  -3,
+		Cost:   0.0125,
+		Wait:   1500 * time.Millisecond,
+		Answer: &answer,
+		JSON:   map[string]int{"hangupcause": 16},
+	}
+	record, err := cel.MarshalEvent(&v)
+	is.NoErr(err)
+	is.Equal(record, []string{"HANGUP", "1530794700.987654", "2018-07-05", "", "-3", "0.0125", "1.5", "1530794705.000000", "", `{"hangupcause":16}`})
+
+	record, err = cel.MarshalEvent(v, cel.WithDecimalComma(true))
+	is.NoErr(err)
+	is.Equal(record[5], "0,0125")
+	is.Equal(record[6], "1,5")
+
+	_, err = cel.MarshalEvent(42)
+	is.Equal(fmt.Sprint(err), "cel: MarshalEvent(int): not a struct")
+	_, err = cel.MarshalEvent(struct {
+		Time time.Time `cel:"1+2"`
+	}{})
+	is.Equal(fmt.Sprint(err), `failed to marshal field Time: cannot marshal joined columns "1+2"`)
+}
+
+func TestMarshalEventRoundTrip(t *testing.T) {
+	is := is.NewRelaxed(t)
+	var e cel.Event
+	line := `"HANGUP","1530794700.987654","Alice","1001","1001","","","2001","from-internal","SIP/1001-00000001","","","3","acc1","1.1","1.1","","","",""`
+	is.NoErr(cel.UnmarshalLine(line, &e))
+	record, err := cel.MarshalEvent(e)
+	is.NoErr(err)
+	is.Equal(`"`+strings.Join(record, `","`)+`"`, line)
+
+	// Zero times round-trip as well.
+	zero := cel.Event{Type: cel.ChanStart, UniqueID: "1.1"}
+	record, err = cel.MarshalEvent(zero)
+	is.NoErr(err)
+	is.Equal(record[cel.ColEventTime], "-")
+	var back cel.Event
+	is.NoErr(cel.UnmarshalEvent(record, &back))
+	is.Equal(back, zero)
+
+	var ptr struct {
+		Time *time.Time `cel:"0"`
+	}
+	ptr.Time = &time.Time{}
+	record, err = cel.MarshalEvent(ptr)
+	is.NoErr(err)
+	is.Equal(record, []string{"-"})
+	is.NoErr(cel.UnmarshalEvent(record, &ptr))
+	is.Equal(ptr.Time, nil)
+}
+
+func TestBoolFormat(t *testing.T) {
+	type event struct {
+		Answered bool `cel:"0"`
+		Billed   bool `cel:"1"`
+	}
+	cases := []struct {
+		name   string
+		opts   []cel.Option
+		record []string
+	}{
+		{"default", nil, []string{"1", "0"}},
+		{"numeric", []cel.Option{cel.WithBoolFormat(cel.BoolNumeric)}, []string{"1", "0"}},
+		{"words", []cel.Option{cel.WithBoolFormat(cel.BoolWords)}, []string{"true", "false"}},
+		{"yes/no", []cel.Option{cel.WithBoolFormat(cel.BoolYesNo)}, []string{"Y", "N"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			is := is.NewRelaxed(t)
+			var v event
+			line := strings.Join(c.record, ",")
+			is.NoErr(cel.UnmarshalLine(line, &v, c.opts...))
+			is.Equal(v, event{true, false})
+			record, err := cel.MarshalEvent(v, c.opts...)
+			is.NoErr(err)
+			is.Equal(record, c.record)
+		})
+	}
+
+	is := is.NewRelaxed(t)
+	var v event
+	is.NoErr(cel.UnmarshalLine("y,n", &v, cel.WithBoolFormat(cel.BoolYesNo)))
+	is.Equal(v, event{true, false})
+	err := cel.UnmarshalLine("1,0", &v, cel.WithBoolFormat(cel.BoolYesNo))
+	is.Equal(fmt.Sprint(err), `failed to map field Answered: unable to convert field value "1" to bool: expected "Y" or "N"`)
+	is.NoErr(cel.UnmarshalLine("true,F", &v))
+	is.Equal(v, event{true, false})
+}
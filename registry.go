@@ -0,0 +1,48 @@
+package cel
+
+import (
+	"sync"
+)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[EventType]func() interface{})
+)
+
+// RegisterEventStruct makes DecodeTyped decode events of type t into the
+// value returned by newStruct, which must be a new pointer to a struct each
+// time. Registering a type again replaces its constructor. It is meant to be
+// called from init functions, but is safe for concurrent use.
+func RegisterEventStruct(t EventType, newStruct func() interface{}) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[t] = newStruct
+}
+
+// DecodeTyped decodes the next record from dec into a struct that is chosen
+// by its event type: the struct returned by the constructor that was
+// registered for the type (see RegisterEventStruct), or an Event for types
+// without one. It returns the pointer to the struct. The event type is taken
+// from column ColEventType before the record is unmarshaled; returns io.EOF
+// at the end of the input.
+func DecodeTyped(dec *Decoder) (interface{}, error) {
+	r, err := dec.DecodeLazy()
+	if err != nil {
+		return nil, err
+	}
+	var t EventType
+	if err := r.Get(ColEventType, &t); err != nil {
+		return nil, err
+	}
+	registryMu.RLock()
+	newStruct, ok := registry[t]
+	registryMu.RUnlock()
+	var v interface{} = &Event{}
+	if ok {
+		v = newStruct()
+	}
+	if err := r.Unmarshal(v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
@@ -0,0 +1,58 @@
+package cel_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VoIPGRID/cel"
+	"github.com/matryer/is"
+)
+
+type hangupEvent struct {
+	Time  time.Time `cel:"1"`
+	Extra struct {
+		HangupCause int    `json:"hangupcause"`
+		DialStatus  string `json:"dialstatus"`
+	} `cel:"19,json"`
+}
+
+type answerEvent struct {
+	ChanName string `cel:"9"`
+}
+
+func TestDecodeTyped(t *testing.T) {
+	is := is.NewRelaxed(t)
+	cel.RegisterEventStruct("HANGUP", func() interface{} { return &hangupEvent{} })
+	cel.RegisterEventStruct(cel.Answer, func() interface{} { return &answerEvent{} })
+
+	in := strings.Join([]string{
+		cel.NewEventBuilder().Line(),
+		cel.NewEventBuilder().Type(cel.Answer).Line(),
+		cel.NewEventBuilder().Type(cel.Hangup).Extra(`{"hangupcause":16,"dialstatus":"ANSWER"}`).Line(),
+	}, "\n")
+	dec := cel.NewDecoder(strings.NewReader(in))
+
+	v, err := cel.DecodeTyped(dec)
+	is.NoErr(err)
+	e, ok := v.(*cel.Event) // not registered
+	is.True(ok)
+	is.Equal(*e, cel.NewEventBuilder().Event())
+
+	v, err = cel.DecodeTyped(dec)
+	is.NoErr(err)
+	a, ok := v.(*answerEvent)
+	is.True(ok)
+	is.Equal(a.ChanName, "SIP/1001-00000001")
+
+	v, err = cel.DecodeTyped(dec)
+	is.NoErr(err)
+	h, ok := v.(*hangupEvent)
+	is.True(ok)
+	is.Equal(h.Extra.HangupCause, 16)
+	is.Equal(h.Extra.DialStatus, "ANSWER")
+
+	_, err = cel.DecodeTyped(dec)
+	is.Equal(err, io.EOF)
+}
@@ -0,0 +1,121 @@
+package cel
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// BlindTransferExtra holds the extra field of a BLINDTRANSFER event.
+//
+// The fields that refer to the transferee are only logged by recent versions
+// of Asterisk, and are empty otherwise. The extra refers to channels by name
+// and uniqueid only; TransferLinks resolves the linkedids of those channels.
+type BlindTransferExtra struct {
+	Extension string `json:"extension"` // Extension the transferee is sent to.
+	Context   string `json:"context"`
+	BridgeID  string `json:"bridge_id"`
+
+	TransfereeChannel  string `json:"transferee_channel_name"`
+	TransfereeUniqueID string `json:"transferee_channel_uniqueid"`
+}
+
+// AttendedTransferExtra holds the extra field of an ATTENDEDTRANSFER event.
+// The event is logged for the transferer's first channel; the second channel
+// is the one the transferer used to consult the transfer target.
+//
+// Depending on what the second channel was doing at the time of the transfer,
+// Bridge2ID holds the bridge it was in, or App holds the dialplan application
+// it was running; the other is empty. The fields that refer to the transferee
+// and the transfer target are only logged by recent versions of Asterisk, and
+// are empty otherwise. As for BlindTransferExtra, linkedids are not logged,
+// but can be resolved using TransferLinks.
+type AttendedTransferExtra struct {
+	Bridge1ID        string `json:"bridge1_id"`
+	Channel2         string `json:"channel2_name"`
+	Channel2UniqueID string `json:"channel2_uniqueid"`
+	Bridge2ID        string `json:"bridge2_id"`
+	App              string `json:"app"`
+
+	TransfereeChannel      string `json:"transferee_channel_name"`
+	TransfereeUniqueID     string `json:"transferee_channel_uniqueid"`
+	TransferTargetChannel  string `json:"transfer_target_channel_name"`
+	TransferTargetUniqueID string `json:"transfer_target_channel_uniqueid"`
+}
+
+// ParseBlindTransferExtra parses the extra field of a BLINDTRANSFER event.
+// Fields that are missing from extra are left empty, and unknown fields are
+// ignored. Returns an error if extra is not a JSON object.
+func ParseBlindTransferExtra(extra string) (BlindTransferExtra, error) {
+	var x BlindTransferExtra
+	if err := json.Unmarshal([]byte(extra), &x); err != nil {
+		return BlindTransferExtra{}, errors.Wrap(err, "bad blind transfer extra")
+	}
+	return x, nil
+}
+
+// ParseAttendedTransferExtra parses the extra field of an ATTENDEDTRANSFER
+// event, like ParseBlindTransferExtra.
+func ParseAttendedTransferExtra(extra string) (AttendedTransferExtra, error) {
+	var x AttendedTransferExtra
+	if err := json.Unmarshal([]byte(extra), &x); err != nil {
+		return AttendedTransferExtra{}, errors.Wrap(err, "bad attended transfer extra")
+	}
+	return x, nil
+}
+
+// A TransferLink holds the linkedids of the calls involved in a transfer.
+type TransferLink struct {
+	Event Event // The BLINDTRANSFER or ATTENDEDTRANSFER event.
+
+	// TransfereeLinkedID is the linkedid of the transferee's channel, and
+	// TargetLinkedID that of the transfer target's channel, or of the
+	// transferer's second channel if the extra does not name the target.
+	// Blind transfers have no target. Either is empty if the channel is not
+	// named in the extra or has no events.
+	TransfereeLinkedID string
+	TargetLinkedID     string
+}
+
+// TransferLinks returns the linkedids involved in the transfers in events,
+// in order. The linkedid of a channel is taken from its last event before
+// the transfer event, as linkedids change when calls are transferred, so
+// events should include the events of all calls involved and be in the
+// order they were logged. Returns an error if the extra field of a transfer
+// event cannot be parsed.
+func TransferLinks(events []Event) ([]TransferLink, error) {
+	var links []TransferLink
+	linkedIDs := make(map[string]string)
+	for _, e := range events {
+		switch e.Type {
+		case BlindTransfer:
+			x, err := ParseBlindTransferExtra(e.Extra)
+			if err != nil {
+				return nil, err
+			}
+			links = append(links, TransferLink{Event: e, TransfereeLinkedID: linkedIDs[x.TransfereeUniqueID]})
+		case AttendedTransfer:
+			x, err := ParseAttendedTransferExtra(e.Extra)
+			if err != nil {
+				return nil, err
+			}
+			target := x.TransferTargetUniqueID
+			if target == "" {
+				target = x.Channel2UniqueID
+			}
+			links = append(links, TransferLink{Event: e, TransfereeLinkedID: linkedIDs[x.TransfereeUniqueID], TargetLinkedID: linkedIDs[target]})
+		}
+		if e.UniqueID != "" {
+			linkedIDs[e.UniqueID] = e.LinkedID
+		}
+	}
+	return links, nil
+}
+
+// TransferLinkedIDs returns the linkedids involved in the transfers of the
+// call, as TransferLinks does for its events. Channels that only have events
+// in other calls cannot be resolved; use TransferLinks on all events for
+// those.
+func (c *Call) TransferLinkedIDs() ([]TransferLink, error) {
+	return TransferLinks(c.Events)
+}
@@ -0,0 +1,109 @@
+package cel_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/VoIPGRID/cel"
+	"github.com/matryer/is"
+)
+
+func TestParseBlindTransferExtra(t *testing.T) {
+	is := is.NewRelaxed(t)
+	cases := []struct {
+		in   string
+		want cel.BlindTransferExtra
+	}{
+		{
+			`{"extension":"2002","context":"from-internal","bridge_id":"b1","transferee_channel_name":"SIP/1001-01","transferee_channel_uniqueid":"1.1"}`,
+			cel.BlindTransferExtra{Extension: "2002", Context: "from-internal", BridgeID: "b1", TransfereeChannel: "SIP/1001-01", TransfereeUniqueID: "1.1"},
+		},
+		{
+			// Older versions of Asterisk do not log the transferee.
+			`{"extension":"2002","context":"from-internal","bridge_id":"b1"}`,
+			cel.BlindTransferExtra{Extension: "2002", Context: "from-internal", BridgeID: "b1"},
+		},
+	}
+	for _, c := range cases {
+		x, err := cel.ParseBlindTransferExtra(c.in)
+		is.NoErr(err)
+		is.Equal(x, c.want)
+	}
+
+	_, err := cel.ParseBlindTransferExtra("")
+	is.True(strings.HasPrefix(err.Error(), "bad blind transfer extra: "))
+}
+
+func TestParseAttendedTransferExtra(t *testing.T) {
+	is := is.NewRelaxed(t)
+	cases := []struct {
+		in   string
+		want cel.AttendedTransferExtra
+	}{
+		{
+			`{"bridge1_id":"b1","channel2_name":"SIP/2001-03","channel2_uniqueid":"1.3","bridge2_id":"b2",` +
+				`"transferee_channel_name":"SIP/1001-01","transferee_channel_uniqueid":"1.1",` +
+				`"transfer_target_channel_name":"SIP/3001-04","transfer_target_channel_uniqueid":"1.4"}`,
+			cel.AttendedTransferExtra{
+				Bridge1ID: "b1", Channel2: "SIP/2001-03", Channel2UniqueID: "1.3", Bridge2ID: "b2",
+				TransfereeChannel: "SIP/1001-01", TransfereeUniqueID: "1.1",
+				TransferTargetChannel: "SIP/3001-04", TransferTargetUniqueID: "1.4",
+			},
+		},
+		{
+			// Transfer to a channel running an application.
+			`{"bridge1_id":"b1","channel2_name":"SIP/2001-03","channel2_uniqueid":"1.3","app":"VoiceMail"}`,
+			cel.AttendedTransferExtra{Bridge1ID: "b1", Channel2: "SIP/2001-03", Channel2UniqueID: "1.3", App: "VoiceMail"},
+		},
+	}
+	for _, c := range cases {
+		x, err := cel.ParseAttendedTransferExtra(c.in)
+		is.NoErr(err)
+		is.Equal(x, c.want)
+	}
+
+	_, err := cel.ParseAttendedTransferExtra("[]")
+	is.True(strings.HasPrefix(err.Error(), "bad attended transfer extra: "))
+}
+
+func TestTransferLinks(t *testing.T) {
+	is := is.NewRelaxed(t)
+	attended := `{"bridge1_id":"b1","channel2_name":"SIP/2001-03","channel2_uniqueid":"1.3","bridge2_id":"b2",` +
+		`"transferee_channel_name":"SIP/1001-01","transferee_channel_uniqueid":"1.1",` +
+		`"transfer_target_channel_name":"SIP/3001-04","transfer_target_channel_uniqueid":"1.4"}`
+	events := []cel.Event{
+		cel.NewEventBuilder().UniqueID("1.1").LinkedID("1.1").Event(),
+		cel.NewEventBuilder().UniqueID("1.2").LinkedID("1.1").Event(),
+		cel.NewEventBuilder().UniqueID("1.3").LinkedID("1.3").Event(),
+		cel.NewEventBuilder().UniqueID("1.4").LinkedID("1.3").Event(),
+		cel.NewEventBuilder().Type(cel.AttendedTransfer).UniqueID("1.2").LinkedID("1.1").Extra(attended).Event(),
+		cel.NewEventBuilder().Type(cel.BridgeEnter).UniqueID("1.4").LinkedID("1.1").Event(), // joins the transferee's call
+		cel.NewEventBuilder().Type(cel.BlindTransfer).UniqueID("1.4").LinkedID("1.1").
+			Extra(`{"extension":"2002","context":"from-internal","bridge_id":"b1","transferee_channel_uniqueid":"1.1"}`).Event(),
+		// Older versions of Asterisk only log the second channel.
+		cel.NewEventBuilder().Type(cel.AttendedTransfer).UniqueID("1.2").LinkedID("1.1").
+			Extra(`{"bridge1_id":"b1","channel2_name":"SIP/2001-03","channel2_uniqueid":"1.3","bridge2_id":"b2"}`).Event(),
+	}
+
+	links, err := cel.TransferLinks(events)
+	is.NoErr(err)
+	is.Equal(links, []cel.TransferLink{
+		{Event: events[4], TransfereeLinkedID: "1.1", TargetLinkedID: "1.3"},
+		{Event: events[6], TransfereeLinkedID: "1.1"},
+		{Event: events[7], TargetLinkedID: "1.3"},
+	})
+
+	// Within a call, channels of other calls are not resolved.
+	calls := cel.GroupByLinkedID(events)
+	links, err = calls[0].TransferLinkedIDs()
+	is.NoErr(err)
+	is.Equal(links, []cel.TransferLink{
+		{Event: events[4], TransfereeLinkedID: "1.1"},
+		{Event: events[6], TransfereeLinkedID: "1.1"},
+		{Event: events[7]},
+	})
+
+	_, err = cel.TransferLinks([]cel.Event{cel.NewEventBuilder().Type(cel.BlindTransfer).Event()})
+	is.True(strings.HasPrefix(fmt.Sprint(err), "bad blind transfer extra: "))
+}
@@ -0,0 +1,87 @@
+package cel
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ParseUserField parses the userfield column as a list of key=value pairs
+// separated by semicolons, as in "queue=sales;agent=SIP/1001". Surrounding
+// whitespace is trimmed from keys and values. Pairs without a '=' are stored
+// with an empty value. For duplicate keys, the last value wins.
+func ParseUserField(s string) map[string]string {
+	m := make(map[string]string)
+	for _, pair := range strings.Split(s, ";") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		var value string
+		if len(kv) == 2 {
+			value = strings.TrimSpace(kv[1])
+		}
+		m[strings.TrimSpace(kv[0])] = value
+	}
+	return m
+}
+
+// QueueUserField holds the queue metadata that dialplans commonly store in
+// the userfield of calls handled by app_queue.
+type QueueUserField struct {
+	Queue    string
+	Agent    string
+	WaitTime time.Duration
+
+	// Other holds the pairs that did not match any of the field names.
+	Other map[string]string
+}
+
+// QueueUserFieldNames holds the userfield keys that are used for each of the
+// QueueUserField fields.
+type QueueUserFieldNames struct {
+	Queue    string
+	Agent    string
+	WaitTime string // The value is expected in (integer) seconds.
+}
+
+// DefaultQueueUserFieldNames are the keys used by ParseQueueUserField.
+var DefaultQueueUserFieldNames = QueueUserFieldNames{
+	Queue:    "queue",
+	Agent:    "agent",
+	WaitTime: "wait",
+}
+
+// ParseQueueUserField parses s using DefaultQueueUserFieldNames.
+func ParseQueueUserField(s string) (QueueUserField, error) {
+	return DefaultQueueUserFieldNames.Parse(s)
+}
+
+// Parse parses userfield s (see ParseUserField) into a QueueUserField. Keys
+// that are missing leave the corresponding field empty. Returns an error if
+// the wait time is not an integer.
+func (n QueueUserFieldNames) Parse(s string) (QueueUserField, error) {
+	var q QueueUserField
+	for k, v := range ParseUserField(s) {
+		switch k {
+		case n.Queue:
+			q.Queue = v
+		case n.Agent:
+			q.Agent = v
+		case n.WaitTime:
+			sec, err := strconv.ParseInt(v, 10, 0)
+			if err != nil {
+				return QueueUserField{}, errors.Wrapf(err, "bad wait time %q", v)
+			}
+			q.WaitTime = time.Duration(sec) * time.Second
+		default:
+			if q.Other == nil {
+				q.Other = make(map[string]string)
+			}
+			q.Other[k] = v
+		}
+	}
+	return q, nil
+}
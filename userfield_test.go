@@ -0,0 +1,50 @@
+package cel_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/VoIPGRID/cel"
+	"github.com/matryer/is"
+)
+
+func TestParseUserField(t *testing.T) {
+	is := is.NewRelaxed(t)
+	is.Equal(cel.ParseUserField(" a=1; b = x=y ;c;;a=2"), map[string]string{"a": "2", "b": "x=y", "c": ""})
+	is.Equal(cel.ParseUserField(""), map[string]string{})
+}
+
+func TestParseQueueUserField(t *testing.T) {
+	is := is.NewRelaxed(t)
+	cases := []struct {
+		in   string
+		want cel.QueueUserField
+	}{
+		{"queue=sales;agent=SIP/1001;wait=42", cel.QueueUserField{Queue: "sales", Agent: "SIP/1001", WaitTime: 42 * time.Second}},
+		{"queue=support", cel.QueueUserField{Queue: "support"}},
+		{"agent=SIP/1002;vip=1", cel.QueueUserField{Agent: "SIP/1002", Other: map[string]string{"vip": "1"}}},
+		{"", cel.QueueUserField{}},
+	}
+	for _, c := range cases {
+		q, err := cel.ParseQueueUserField(c.in)
+		is.NoErr(err)
+		is.Equal(q, c.want)
+	}
+
+	_, err := cel.ParseQueueUserField("queue=sales;wait=soon")
+	is.Equal(fmt.Sprint(err), `bad wait time "soon": strconv.ParseInt: parsing "soon": invalid syntax`)
+}
+
+func TestQueueUserFieldNames(t *testing.T) {
+	is := is.NewRelaxed(t)
+	names := cel.QueueUserFieldNames{Queue: "Q", Agent: "MEMBER", WaitTime: "HOLDTIME"}
+	q, err := names.Parse("Q=sales;MEMBER=PJSIP/2001;HOLDTIME=7;queue=ignored")
+	is.NoErr(err)
+	is.Equal(q, cel.QueueUserField{
+		Queue:    "sales",
+		Agent:    "PJSIP/2001",
+		WaitTime: 7 * time.Second,
+		Other:    map[string]string{"queue": "ignored"},
+	})
+}